@@ -0,0 +1,56 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 写入时自动建子表（INSERT ... USING ... TAGS）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InsertAuto 插入一行，子表不存在时按超级表与 TAGS 自动创建
+// 生成 INSERT INTO sub USING stable TAGS (...) (...) VALUES (...)，建表与写入在同一语句内完成
+func (c *Client) InsertAuto(sub string, stable string, tagValues []interface{}, row map[string]interface{}) error {
+	return c.BatchInsertAuto(sub, stable, tagValues, []map[string]interface{}{row})
+}
+
+// BatchInsertAuto 批量插入多行，子表不存在时自动创建
+func (c *Client) BatchInsertAuto(sub string, stable string, tagValues []interface{}, rows []map[string]interface{}) error {
+	if stable == "" {
+		return fmt.Errorf("stable 不能为空")
+	}
+	return c.BatchInsertTables([]TableRows{{Table: sub, Stable: stable, Tags: tagValues, Rows: rows}})
+}
+
+// formatTagValues 将 TAG 值列表格式化为逗号分隔的字面量
+func (vc *valueCodec) formatTagValues(tagValues []interface{}) (string, error) {
+	vals := make([]string, 0, len(tagValues))
+	for _, v := range tagValues {
+		fv, err := vc.formatTagValue(v)
+		if err != nil {
+			return "", err
+		}
+		vals = append(vals, fv)
+	}
+	return strings.Join(vals, ", "), nil
+}
+
+// InsertAutoMsg 自动建表插入并返回提示
+func (c *Client) InsertAutoMsg(sub string, stable string, tagValues []interface{}, row map[string]interface{}) (string, error) {
+	if err := c.InsertAuto(sub, stable, tagValues, row); err != nil {
+		return "", fmt.Errorf("InsertAuto into %s using %s failed: %w", sub, stable, err)
+	}
+	return fmt.Sprintf("已写入 1 行到 %s (USING %s)", sub, stable), nil
+}
+
+// BatchInsertAutoMsg 自动建表批量插入并返回提示
+func (c *Client) BatchInsertAutoMsg(sub string, stable string, tagValues []interface{}, rows []map[string]interface{}) (string, error) {
+	if err := c.BatchInsertAuto(sub, stable, tagValues, rows); err != nil {
+		return "", fmt.Errorf("BatchInsertAuto into %s using %s failed: %w", sub, stable, err)
+	}
+	return fmt.Sprintf("已批量写入 %d 行到 %s (USING %s)", len(rows), sub, stable), nil
+}
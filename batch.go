@@ -0,0 +1,705 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 多表批量写入
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+// TableRows 某个表的待写入数据
+// Stable 非空时生成 USING stable TAGS (...)，子表不存在会自动创建
+type TableRows struct {
+	Table  string
+	Stable string
+	Tags   []interface{}
+	Rows   []map[string]interface{}
+}
+
+// BatchInsertMulti 在一条语句中向多个表批量插入，key 为表名
+// 表按名称排序后拼接，生成 INSERT INTO t1 (...) VALUES ... t2 (...) VALUES ...
+func (c *Client) BatchInsertMulti(data map[string][]map[string]interface{}) error {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tables := make([]TableRows, 0, len(names))
+	for _, name := range names {
+		tables = append(tables, TableRows{Table: name, Rows: data[name]})
+	}
+	return c.BatchInsertTables(tables)
+}
+
+// BatchInsertTables 向多个表批量插入，可为每个表指定 USING/TAGS
+// 语句超过 SQL 长度上限时自动拆分
+func (c *Client) BatchInsertTables(tables []TableRows) error {
+	_, err := c.BatchInsertTablesWithResult(tables)
+	return err
+}
+
+// MissingColumnPolicy 批量写入时某行缺少其他行含有的列的处理方式
+type MissingColumnPolicy int
+
+const (
+	// MissingUntouched 按列集合将行分组，各组使用各自的列清单，缺失列保持原值（默认）
+	MissingUntouched MissingColumnPolicy = iota
+	// MissingAsNull 所有行使用列的并集，缺失列写入 NULL
+	MissingAsNull
+)
+
+// BatchOptions 批量写入配置
+type BatchOptions struct {
+	MaxSQLBytes    int                 // 单条语句的字节上限，<=0 时使用服务端 maxSQLLength
+	Concurrency    int                 // 拆分后语句的并发执行数，<=1 时顺序执行
+	MissingColumns MissingColumnPolicy // 稀疏行的处理方式
+	// IsolateFailures 语句因数据错误被拒绝时二分重试以定位坏行，其余行正常写入；
+	// 此时错误类型为 *BatchError
+	IsolateFailures bool
+
+	noEvolve bool // 不触发模式演进（如 UpdateWhere 的重写）
+}
+
+// ChunkResult 拆分后单条语句的执行结果
+type ChunkResult struct {
+	Indexes []int // 本条语句包含的行序号（按表顺序展开后的原始行序号）
+	Rows    int   // 本条语句包含的行数
+	Err     error
+}
+
+// BatchResult 批量写入的汇总结果
+type BatchResult struct {
+	Written int // 成功写入的行数
+	Failed  int // 所在语句失败的行数
+	Chunks  []ChunkResult
+}
+
+// Err 汇总各语句的错误，全部成功时返回 nil
+func (r BatchResult) Err() error {
+	var errs []error
+	for _, ch := range r.Chunks {
+		if ch.Err != nil {
+			errs = append(errs, fmt.Errorf("%d rows starting at #%d: %w", ch.Rows, ch.Indexes[0], ch.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RowError 单行写入失败的原因
+type RowError struct {
+	Index int // 按表顺序展开后的原始行序号
+	Err   error
+}
+
+// BatchError 启用 IsolateFailures 时批量写入的错误，逐行列出失败原因
+type BatchError struct {
+	Written int
+	Rows    []RowError
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Rows) == 0 {
+		return "批量写入失败"
+	}
+	return fmt.Sprintf("批量写入 %d 行失败（成功 %d 行），首个失败为第 %d 行: %v",
+		len(e.Rows), e.Written, e.Rows[0].Index, e.Rows[0].Err)
+}
+
+// Unwrap 支持 errors.Is/As 匹配各行的错误
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Rows))
+	for i, r := range e.Rows {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+// batchError 将失败语句展开为逐行错误，全部成功时返回 nil
+func (r BatchResult) batchError() error {
+	be := &BatchError{Written: r.Written}
+	for _, ch := range r.Chunks {
+		if ch.Err == nil {
+			continue
+		}
+		for _, idx := range ch.Indexes {
+			be.Rows = append(be.Rows, RowError{Index: idx, Err: ch.Err})
+		}
+	}
+	if len(be.Rows) == 0 {
+		return nil
+	}
+	sort.Slice(be.Rows, func(i, j int) bool { return be.Rows[i].Index < be.Rows[j].Index })
+	return be
+}
+
+// defaultMaxSQLLength 服务端未返回 maxSQLLength 时的默认上限（1MB）
+const defaultMaxSQLLength = 1 << 20
+
+// SetBatchOptions 设置批量写入配置
+func (c *Client) SetBatchOptions(opts BatchOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batchOpts = opts
+}
+
+// BatchInsertWithResult 批量插入单表，返回每条拆分语句的写入情况
+func (c *Client) BatchInsertWithResult(table string, rows []map[string]interface{}) (BatchResult, error) {
+	return c.BatchInsertTablesWithResult([]TableRows{{Table: table, Rows: rows}})
+}
+
+// BatchInsertTablesWithResult 批量插入多表，返回每条拆分语句的写入情况
+func (c *Client) BatchInsertTablesWithResult(tables []TableRows) (BatchResult, error) {
+	return c.batchInsertTables(tables, c.batchOptions())
+}
+
+// batchOptions 返回当前的批量写入配置
+func (c *Client) batchOptions() BatchOptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.batchOpts
+}
+
+// batchInsertTables 按指定配置批量插入多表
+func (c *Client) batchInsertTables(tables []TableRows, opts BatchOptions) (BatchResult, error) {
+	tables, err := c.validateTables(tables)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	vc := c.codec()
+	segs, err := buildInsertSegments(tables, opts.MissingColumns, c.tsFillerFor, vc)
+	if err != nil || len(segs) == 0 {
+		return BatchResult{}, err
+	}
+	if err := c.skipCachedTags(tables, segs, vc); err != nil {
+		return BatchResult{}, err
+	}
+	budget := opts.MaxSQLBytes
+	if budget <= 0 {
+		budget = c.maxSQLLength()
+	}
+	chunks, err := packInsertChunks(segs, budget)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	var evolve func(ch insertChunk) (bool, error)
+	if c.hasSchemaEvolution() && !opts.noEvolve {
+		evolve = c.chunkEvolver(tables)
+	}
+	res := c.execInsertChunks(chunks, opts, evolve)
+	if sc := c.subTableCache(); sc != nil {
+		for _, t := range tables {
+			if t.Stable != "" && len(t.Rows) > 0 && res.Failed == 0 {
+				tagList, _ := vc.formatTagValues(t.Tags)
+				sc.applied(t.Table, t.Stable, tagList)
+			}
+		}
+	}
+	if opts.IsolateFailures {
+		return res, res.batchError()
+	}
+	return res, res.Err()
+}
+
+// execInsertChunks 执行拆分后的语句，Concurrency>1 时并发执行
+// evolve 非空时，因未知列失败的语句在自动加列后重试一次；
+// 启用 IsolateFailures 时，因某行的值被拒绝的语句会被二分重试，结果按行拆开
+func (c *Client) execInsertChunks(chunks []insertChunk, opts BatchOptions, evolve func(ch insertChunk) (bool, error)) BatchResult {
+	parts := make([][]ChunkResult, len(chunks))
+	run := func(i int) {
+		ch := chunks[i]
+		_, err := c.DB.Exec(ch.sql)
+		if err != nil && isTableNotExist(err) && ch.skippedTags() {
+			// 缓存命中而省略了建表子句，但子表已在别处被删除：带上 USING ... TAGS 重试一次
+			ch.items = withCreateHeaders(ch.items)
+			ch.sql = renderChunkItems(ch.items)
+			_, err = c.DB.Exec(ch.sql)
+		}
+		if err != nil && evolve != nil && isUnknownColumn(err) {
+			if retry, eerr := evolve(ch); eerr != nil {
+				err = fmt.Errorf("%w（自动加列失败: %v）", err, eerr)
+			} else if retry {
+				_, err = c.DB.Exec(ch.sql)
+			}
+		}
+		for _, tbl := range ch.tables {
+			c.invalidateOnMissing(tbl, err)
+		}
+		if err != nil && opts.IsolateFailures && ch.rows > 1 && isRowDataError(err) {
+			parts[i] = c.bisectInsert(ch.items, err)
+			return
+		}
+		parts[i] = []ChunkResult{{Indexes: ch.indexes, Rows: ch.rows, Err: err}}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 1 || len(chunks) == 1 {
+		for i := range chunks {
+			run(i)
+		}
+	} else {
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for i := range chunks {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	}
+	var res BatchResult
+	for _, p := range parts {
+		res.Chunks = append(res.Chunks, p...)
+	}
+	for _, ch := range res.Chunks {
+		if ch.Err != nil {
+			res.Failed += ch.Rows
+		} else {
+			res.Written += ch.Rows
+		}
+	}
+	return res
+}
+
+// chunkEvolver 返回按语句中的行执行自动加列的函数，返回值表示是否应重试该语句
+func (c *Client) chunkEvolver(tables []TableRows) func(ch insertChunk) (bool, error) {
+	type flatRow struct {
+		table, stable string
+		row           map[string]interface{}
+	}
+	var flat []flatRow
+	for _, t := range tables {
+		for _, r := range t.Rows {
+			flat = append(flat, flatRow{t.Table, t.Stable, r})
+		}
+	}
+	return func(ch insertChunk) (bool, error) {
+		byTable := map[string][]map[string]interface{}{}
+		var order []flatRow
+		for _, idx := range ch.indexes {
+			fr := flat[idx]
+			key := strings.ToLower(fr.table)
+			if _, ok := byTable[key]; !ok {
+				order = append(order, fr)
+			}
+			byTable[key] = append(byTable[key], fr.row)
+		}
+		retry := false
+		for _, fr := range order {
+			ok, err := c.evolveSchema(fr.table, fr.stable, byTable[strings.ToLower(fr.table)])
+			if err != nil {
+				return false, err
+			}
+			retry = retry || ok
+		}
+		return retry, nil
+	}
+}
+
+// bisectInsert 将失败语句的行二分后分别重试，直至定位到单个坏行；返回各部分的执行结果
+// 只有行级数据错误（isRowDataError）才会继续拆分，语句级错误按半段整体报告
+func (c *Client) bisectInsert(items []chunkItem, err error) []ChunkResult {
+	if len(items) == 1 {
+		return []ChunkResult{{Indexes: []int{items[0].index}, Rows: 1, Err: err}}
+	}
+	mid := len(items) / 2
+	halves := [][]chunkItem{items[:mid], items[mid:]}
+	errs := make([]error, len(halves))
+	for i, half := range halves {
+		_, errs[i] = c.DB.Exec(renderChunkItems(half))
+	}
+	var out []ChunkResult
+	for i, half := range halves {
+		if errs[i] != nil && isRowDataError(errs[i]) {
+			out = append(out, c.bisectInsert(half, errs[i])...)
+			continue
+		}
+		out = append(out, ChunkResult{Indexes: itemIndexes(half), Rows: len(half), Err: errs[i]})
+	}
+	return out
+}
+
+func itemIndexes(items []chunkItem) []int {
+	idx := make([]int, len(items))
+	for i, it := range items {
+		idx[i] = it.index
+	}
+	return idx
+}
+
+// 语句级的永久错误码（taoserror.h）：重试同一语句不会成功。
+// 其他错误码（taosd 不可用、vnode 主节点切换、内存不足、写入限流等）即使以 TaosError 返回也视为可重试
+const (
+	codeTscSyntaxError     = 0x0216 // TSC_SQL_SYNTAX_ERROR
+	codeMndStbNotExist     = 0x0362 // MND_STB_NOT_EXIST
+	codeTdbTableNotExist   = 0x0603 // TDB_TABLE_NOT_EXIST
+	codeTdbTsOutOfRange    = 0x060B // TDB_TIMESTAMP_OUT_OF_RANGE
+	codeParSyntaxError     = 0x2600 // PAR_SYNTAX_ERROR，非法的值格式同样以此返回
+	codeParInvalidColumn   = 0x2602 // PAR_INVALID_COLUMN
+	codeParTableNotExist   = 0x2603 // PAR_TABLE_NOT_EXIST
+	codeParWrongValueType  = 0x2605 // PAR_WRONG_VALUE_TYPE
+	codeParValueTooLong    = 0x2653 // PAR_VALUE_TOO_LONG
+	codeParGetMetaNotExist = 0x2662 // PAR_TABLE_NOT_EXIST（获取元数据时）
+)
+
+// permanentCodes 语句本身有误、重试无意义的错误码：语法、非法值或类型、值超长、表或列不存在
+var permanentCodes = map[int32]bool{
+	codeTscSyntaxError:     true,
+	codeMndStbNotExist:     true,
+	codeTdbTableNotExist:   true,
+	codeTdbTsOutOfRange:    true,
+	codeParSyntaxError:     true,
+	codeParInvalidColumn:   true,
+	codeParTableNotExist:   true,
+	codeParWrongValueType:  true,
+	codeParValueTooLong:    true,
+	codeParGetMetaNotExist: true,
+}
+
+// rowDataCodes 由某一行的值引起的错误码，可通过二分定位坏行；
+// 表不存在、鉴权、库不存在、vnode 只读或不可用、限流等整条语句级的错误不在其中
+var rowDataCodes = map[int32]bool{
+	codeTscSyntaxError:    true,
+	codeTdbTsOutOfRange:   true,
+	codeParSyntaxError:    true,
+	codeParWrongValueType: true,
+	codeParValueTooLong:   true,
+}
+
+// taosCode 返回服务端错误码，非 TaosError 时 ok 为 false
+func taosCode(err error) (int32, bool) {
+	var te *taosErrors.TaosError
+	if !errors.As(err, &te) {
+		return 0, false
+	}
+	return te.Code, true
+}
+
+// isRowDataError 由某一行的值引起、值得二分定位的错误
+func isRowDataError(err error) bool {
+	code, ok := taosCode(err)
+	return ok && rowDataCodes[code]
+}
+
+// isPermanentError 服务端因语句内容拒绝执行的错误：permanentCodes 中的错误码以及
+// 解析器（PAR_*，0x2600-0x26FF）返回的全部错误；网络错误与服务端暂时不可用等错误返回 false
+func isPermanentError(err error) bool {
+	code, ok := taosCode(err)
+	return ok && (permanentCodes[code] || code&^0xFF == codeParSyntaxError)
+}
+
+// isUnavailableError 未到达服务端或连接类的错误（非 TaosError，或 RPC/通用错误码 0x0000-0x00FF）
+func isUnavailableError(err error) bool {
+	code, ok := taosCode(err)
+	return !ok || code < 0x0100
+}
+
+// maxSQLLength 读取服务端（taosAdapter 所用客户端）的 maxSQLLength，结果会被缓存
+func (c *Client) maxSQLLength() int {
+	c.mu.RLock()
+	n := c.maxSQLLen
+	c.mu.RUnlock()
+	if n > 0 {
+		return n
+	}
+	n = defaultMaxSQLLength
+	if rows, err := c.queryMaps("SHOW LOCAL VARIABLES"); err == nil {
+		for _, r := range rows {
+			if strings.EqualFold(rowString(r, "name"), "maxSQLLength") {
+				var v int
+				if _, err := fmt.Sscanf(rowString(r, "value"), "%d", &v); err == nil && v > 0 {
+					n = v
+				}
+				break
+			}
+		}
+	}
+	c.mu.Lock()
+	c.maxSQLLen = n
+	c.mu.Unlock()
+	return n
+}
+
+// insertSegment 单个表（或其中一个列集合分组）的写入片段
+type insertSegment struct {
+	table   string
+	header  string   // "tbl [USING st TAGS (...)] (列...) VALUES"
+	created string   // 因子表缓存命中省略建表子句时，带 USING ... TAGS 的原表头
+	groups  []string // 每行的 "(...)" 值组
+	indexes []int    // 每行的原始行序号
+}
+
+// insertChunk 一条可执行的 INSERT 语句
+type insertChunk struct {
+	sql     string
+	indexes []int
+	rows    int
+	tables  []string
+	items   []chunkItem // 组成语句的各行，用于坏行隔离时重新拼装
+}
+
+// chunkItem 语句中的一行及其所属片段
+type chunkItem struct {
+	header  string
+	created string // 见 insertSegment.created
+	group   string
+	index   int
+}
+
+// skippedTags 判断语句中是否有因子表缓存命中而省略了建表子句的行
+func (ch insertChunk) skippedTags() bool {
+	for _, it := range ch.items {
+		if it.created != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// withCreateHeaders 将省略了建表子句的行恢复为带 USING ... TAGS 的表头
+func withCreateHeaders(items []chunkItem) []chunkItem {
+	out := make([]chunkItem, len(items))
+	for i, it := range items {
+		if it.created != "" {
+			it.header, it.created = it.created, ""
+		}
+		out[i] = it
+	}
+	return out
+}
+
+// skipCachedTags 对子表缓存中已存在且 TAGS 一致的子表省略 USING ... TAGS 子句，减少服务端解析与建表检查
+// 原表头保存在 created 中，子表在别处被删除时据此重试
+func (c *Client) skipCachedTags(tables []TableRows, segs []insertSegment, vc *valueCodec) error {
+	sc := c.subTableCache()
+	if sc == nil {
+		return nil
+	}
+	hits := map[string]string{} // 表名 -> 省略的 " USING ... TAGS (...)"
+	for _, t := range tables {
+		if t.Stable == "" || len(t.Rows) == 0 {
+			continue
+		}
+		tbl, err := sanitizeIdent(t.Table)
+		if err != nil {
+			return err
+		}
+		st, err := sanitizeIdent(t.Stable)
+		if err != nil {
+			return err
+		}
+		tagList, err := vc.formatTagValues(t.Tags)
+		if err != nil {
+			return err
+		}
+		if sc.hit(tbl, st, tagList) {
+			hits[tbl] = fmt.Sprintf(" USING %s TAGS (%s)", st, tagList)
+		}
+	}
+	for i := range segs {
+		using, ok := hits[segs[i].table]
+		if !ok || !strings.HasPrefix(segs[i].header, segs[i].table+using) {
+			continue
+		}
+		segs[i].created = segs[i].header
+		segs[i].header = segs[i].table + strings.TrimPrefix(segs[i].header, segs[i].table+using)
+	}
+	return nil
+}
+
+// renderChunkItems 将若干行重新拼装为一条 INSERT 语句，相邻同片段的行共用表头
+func renderChunkItems(items []chunkItem) string {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO")
+	for i, it := range items {
+		if i == 0 || items[i-1].header != it.header {
+			sb.WriteString(" " + it.header)
+		}
+		sb.WriteString(" " + it.group)
+	}
+	return sb.String()
+}
+
+// buildInsertSegments 为每个有数据的表生成写入片段，行序号按 tables 顺序展开
+// fillFor 为每个表创建缺失 ts 的生成器，为 nil 时使用 NOW()
+func buildInsertSegments(tables []TableRows, missing MissingColumnPolicy, fillFor func(table string) tsFiller, vc *valueCodec) ([]insertSegment, error) {
+	segs := make([]insertSegment, 0, len(tables))
+	base := 0
+	for _, t := range tables {
+		if len(t.Rows) == 0 {
+			continue
+		}
+		var fill tsFiller
+		if fillFor != nil {
+			fill = fillFor(t.Table)
+		}
+		tableSegs, err := buildTableSegments(t, missing, fill, base, vc)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, tableSegs...)
+		base += len(t.Rows)
+	}
+	return segs, nil
+}
+
+// buildTableSegments 生成单个表的写入片段；MissingUntouched 时每个列集合一个片段
+func buildTableSegments(t TableRows, missing MissingColumnPolicy, fill tsFiller, base int, vc *valueCodec) ([]insertSegment, error) {
+	tbl, err := sanitizeIdent(t.Table)
+	if err != nil {
+		return nil, err
+	}
+	prefix := tbl
+	if t.Stable != "" {
+		st, err := sanitizeIdent(t.Stable)
+		if err != nil {
+			return nil, err
+		}
+		tagList, err := vc.formatTagValues(t.Tags)
+		if err != nil {
+			return nil, err
+		}
+		prefix += fmt.Sprintf(" USING %s TAGS (%s)", st, tagList)
+	}
+	if fill != nil {
+		// 按输入顺序为缺少 ts 的行分配时间戳，之后按列集合分组不影响先后
+		lits := make(map[int]string)
+		for k, r := range t.Rows {
+			if _, ok := lookupTag(r, "ts"); ok {
+				continue
+			}
+			lit, err := fill(k)
+			if err != nil {
+				return nil, err
+			}
+			lits[k] = lit
+		}
+		fill = func(k int) (string, error) { return lits[k], nil }
+	}
+	var parts [][]int
+	if missing == MissingAsNull {
+		all := make([]int, len(t.Rows))
+		for i := range all {
+			all[i] = i
+		}
+		parts = [][]int{all}
+	} else {
+		parts = groupByColumnSet(t.Rows)
+	}
+	segs := make([]insertSegment, 0, len(parts))
+	for _, idx := range parts {
+		rows := make([]map[string]interface{}, len(idx))
+		indexes := make([]int, len(idx))
+		for i, k := range idx {
+			rows[i] = t.Rows[k]
+			indexes[i] = base + k
+		}
+		var segFill tsFiller
+		if fill != nil {
+			segFill = func(i int) (string, error) { return fill(idx[i]) }
+		}
+		cols, groups, err := buildInsertValues(rows, segFill, vc)
+		if err != nil {
+			return nil, err
+		}
+		header := fmt.Sprintf("%s (%s) VALUES", prefix, strings.Join(cols, ", "))
+		segs = append(segs, insertSegment{table: tbl, header: header, groups: groups, indexes: indexes})
+	}
+	return segs, nil
+}
+
+// groupByColumnSet 按行的列集合（忽略 ts）分组，保持各组首次出现的顺序
+func groupByColumnSet(rows []map[string]interface{}) [][]int {
+	idx := map[string]int{}
+	var parts [][]int
+	for i, r := range rows {
+		keys := make([]string, 0, len(r))
+		for k := range r {
+			if !strings.EqualFold(k, "ts") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		sig := strings.Join(keys, ",")
+		g, ok := idx[sig]
+		if !ok {
+			g = len(parts)
+			idx[sig] = g
+			parts = append(parts, nil)
+		}
+		parts[g] = append(parts[g], i)
+	}
+	return parts
+}
+
+// packInsertChunks 按字节上限将片段装配为若干条 INSERT 语句
+// 单行超过上限时返回错误
+func packInsertChunks(segs []insertSegment, budget int) ([]insertChunk, error) {
+	const prefix = "INSERT INTO"
+	var chunks []insertChunk
+	var sb strings.Builder
+	cur := insertChunk{}
+	finish := func() {
+		if cur.rows > 0 {
+			cur.sql = sb.String()
+			chunks = append(chunks, cur)
+		}
+		sb.Reset()
+		cur = insertChunk{}
+	}
+	for _, seg := range segs {
+		open := false
+		for k, g := range seg.groups {
+			need := len(g) + 1
+			if !open {
+				need += len(seg.header) + 1
+			}
+			if cur.rows > 0 && len(prefix)+sb.Len()+need > budget {
+				finish()
+				open = false
+				need = len(seg.header) + 1 + len(g) + 1
+			}
+			if len(prefix)+need > budget {
+				return nil, fmt.Errorf("单行长度超过 SQL 上限 %d 字节: %s", budget, seg.table)
+			}
+			if !open {
+				sb.WriteString(" " + seg.header)
+				cur.tables = append(cur.tables, seg.table)
+				open = true
+			}
+			sb.WriteString(" " + g)
+			cur.indexes = append(cur.indexes, seg.indexes[k])
+			cur.items = append(cur.items, chunkItem{header: seg.header, created: seg.created, group: g, index: seg.indexes[k]})
+			cur.rows++
+		}
+	}
+	finish()
+	for i := range chunks {
+		chunks[i].sql = prefix + chunks[i].sql
+	}
+	return chunks, nil
+}
+
+// BatchInsertMultiMsg 多表批量插入并返回提示
+func (c *Client) BatchInsertMultiMsg(data map[string][]map[string]interface{}) (string, error) {
+	if err := c.BatchInsertMulti(data); err != nil {
+		return "", fmt.Errorf("BatchInsertMulti failed: %w", err)
+	}
+	total := 0
+	for _, rows := range data {
+		total += len(rows)
+	}
+	return fmt.Sprintf("已批量写入 %d 行到 %d 个表", total, len(data)), nil
+}
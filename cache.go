@@ -0,0 +1,282 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 本地子表存在性与 TAG 缓存（LRU + TTL）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SubTableCacheOptions 子表缓存配置
+type SubTableCacheOptions struct {
+	MaxEntries int           // 最大缓存子表数，<=0 时默认 10000
+	TTL        time.Duration // 缓存有效期，<=0 表示不过期
+}
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+type subTableEntry struct {
+	key     string
+	stable  string
+	tags    string // 格式化后的 TAGS 值
+	expires time.Time
+}
+
+// subTableCache 记录已确认存在的子表及其 TAGS
+type subTableCache struct {
+	mu    sync.Mutex
+	opts  SubTableCacheOptions
+	ll    *list.List
+	items map[string]*list.Element
+	stats CacheStats
+}
+
+func newSubTableCache(opts SubTableCacheOptions) *subTableCache {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	return &subTableCache{opts: opts, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// hit 判断子表是否已知存在且 TAGS 一致
+func (sc *subTableCache) hit(sub, stable, tags string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	el, ok := sc.items[strings.ToLower(sub)]
+	if !ok {
+		sc.stats.Misses++
+		return false
+	}
+	e := el.Value.(*subTableEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		sc.removeElement(el)
+		sc.stats.Misses++
+		return false
+	}
+	if !strings.EqualFold(e.stable, stable) || e.tags != tags {
+		sc.stats.Misses++
+		return false
+	}
+	sc.ll.MoveToFront(el)
+	sc.stats.Hits++
+	return true
+}
+
+func (sc *subTableCache) add(sub, stable, tags string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	key := strings.ToLower(sub)
+	var expires time.Time
+	if sc.opts.TTL > 0 {
+		expires = time.Now().Add(sc.opts.TTL)
+	}
+	if el, ok := sc.items[key]; ok {
+		e := el.Value.(*subTableEntry)
+		e.stable, e.tags, e.expires = stable, tags, expires
+		sc.ll.MoveToFront(el)
+		return
+	}
+	sc.items[key] = sc.ll.PushFront(&subTableEntry{key: key, stable: stable, tags: tags, expires: expires})
+	for sc.ll.Len() > sc.opts.MaxEntries {
+		sc.removeElement(sc.ll.Back())
+		sc.stats.Evictions++
+	}
+}
+
+// applied 在 CREATE TABLE IF NOT EXISTS（或 INSERT ... USING ... TAGS）成功后记录子表
+// 子表已在缓存中时该语句不会修改 TAGS，只刷新有效期并保留已记录的 TAGS，
+// 只有首次见到的子表才记录本次的 TAGS
+func (sc *subTableCache) applied(sub, stable, tags string) {
+	sc.mu.Lock()
+	el, ok := sc.items[strings.ToLower(sub)]
+	if ok {
+		e := el.Value.(*subTableEntry)
+		if strings.EqualFold(e.stable, stable) && e.tags != tags {
+			tags = e.tags
+		}
+	}
+	sc.mu.Unlock()
+	sc.add(sub, stable, tags)
+}
+
+// reset 清空缓存，保留命中统计
+func (sc *subTableCache) reset() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.ll.Init()
+	sc.items = make(map[string]*list.Element)
+}
+
+// stableFor 返回缓存中子表所属的超级表（不计入命中统计）
+func (sc *subTableCache) stableFor(sub string) (string, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	el, ok := sc.items[strings.ToLower(sub)]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*subTableEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		return "", false
+	}
+	return e.stable, true
+}
+
+func (sc *subTableCache) remove(sub string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if el, ok := sc.items[strings.ToLower(sub)]; ok {
+		sc.removeElement(el)
+	}
+}
+
+// removeStable 移除某个超级表下的全部子表
+func (sc *subTableCache) removeStable(stable string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, el := range sc.items {
+		if strings.EqualFold(el.Value.(*subTableEntry).stable, stable) {
+			sc.removeElement(el)
+		}
+	}
+}
+
+func (sc *subTableCache) removeElement(el *list.Element) {
+	sc.ll.Remove(el)
+	delete(sc.items, el.Value.(*subTableEntry).key)
+}
+
+func (sc *subTableCache) snapshot() CacheStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	st := sc.stats
+	st.Size = sc.ll.Len()
+	return st
+}
+
+// EnableSubTableCache 启用子表缓存：EnsureSubTable 命中时不再访问数据库
+// 重复调用会以新配置重建缓存
+func (c *Client) EnableSubTableCache(opts SubTableCacheOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subCache = newSubTableCache(opts)
+}
+
+// DisableSubTableCache 关闭并清空子表缓存
+func (c *Client) DisableSubTableCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subCache = nil
+}
+
+// SubTableCacheStats 返回缓存命中统计；未启用时返回零值
+func (c *Client) SubTableCacheStats() CacheStats {
+	sc := c.subTableCache()
+	if sc == nil {
+		return CacheStats{}
+	}
+	return sc.snapshot()
+}
+
+// InvalidateSubTable 使某个子表的缓存失效
+func (c *Client) InvalidateSubTable(sub string) {
+	if sc := c.subTableCache(); sc != nil {
+		sc.remove(sub)
+	}
+}
+
+// DropTable 删除子表或普通表，并使缓存失效
+func (c *Client) DropTable(table string, ifExists bool) error {
+	tbl, err := sanitizeIdent(table)
+	if err != nil {
+		return err
+	}
+	sqlStr := "DROP TABLE "
+	if ifExists {
+		sqlStr += "IF EXISTS "
+	}
+	// 执行前后各失效一次：执行期间并发的 EnsureSubTable 可能重新写入缓存
+	c.InvalidateSubTable(tbl)
+	c.InvalidateSchema(tbl)
+	_, err = c.DB.Exec(sqlStr + tbl)
+	c.InvalidateSubTable(tbl)
+	c.InvalidateSchema(tbl)
+	return err
+}
+
+// DropStable 删除超级表（连同全部子表），并使相关缓存失效
+func (c *Client) DropStable(stable string, ifExists bool) error {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return err
+	}
+	sqlStr := "DROP STABLE "
+	if ifExists {
+		sqlStr += "IF EXISTS "
+	}
+	invalidate := func() {
+		if sc := c.subTableCache(); sc != nil {
+			sc.removeStable(st)
+		}
+		c.InvalidateSchema("")
+	}
+	// 执行前后各失效一次，理由同 DropTable
+	invalidate()
+	_, err = c.DB.Exec(sqlStr + st)
+	invalidate()
+	return err
+}
+
+// SetSubTableTag 修改子表的 TAG 值，并使缓存失效
+func (c *Client) SetSubTableTag(sub string, tag string, value interface{}) error {
+	subName, err := sanitizeIdent(sub)
+	if err != nil {
+		return err
+	}
+	tagName, err := sanitizeIdent(tag)
+	if err != nil {
+		return err
+	}
+	fv, err := c.codec().formatTagValue(value)
+	if err != nil {
+		return err
+	}
+	c.InvalidateSubTable(subName)
+	_, err = c.DB.Exec(fmt.Sprintf("ALTER TABLE %s SET TAG %s = %s", subName, tagName, fv))
+	c.InvalidateSubTable(subName)
+	return err
+}
+
+func (c *Client) subTableCache() *subTableCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.subCache
+}
+
+// invalidateOnMissing 当错误为“表不存在”时使缓存失效，原样返回错误
+func (c *Client) invalidateOnMissing(table string, err error) error {
+	if err != nil && isTableNotExist(err) {
+		c.InvalidateSubTable(table)
+	}
+	return err
+}
+
+// isTableNotExist 判断错误是否为“表不存在”
+func isTableNotExist(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "table does not exist") || strings.Contains(msg, "table not exist")
+}
@@ -14,15 +14,43 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/taosdata/driver-go/v3/taosRestful"
+	"github.com/taosdata/driver-go/v3/taosRestful"
 )
 
 // Client 封装 TDengine REST 连接
 type Client struct {
 	DB *sql.DB
+
+	mu       sync.RWMutex
+	namers   map[string]*subTableNaming // 超级表 -> 子表命名策略
+	subCache *subTableCache             // 子表存在性缓存，nil 表示未启用
+
+	batchOpts BatchOptions // 批量写入配置
+	maxSQLLen int          // 缓存的服务端 maxSQLLength
+
+	tsPolicy TimestampPolicy // 未提供 ts 时的时间戳策略
+	tsClock  tsClock         // 客户端时钟策略下各表最近分配的时间戳
+
+	validate bool                    // 是否开启写入前校验
+	schemas  map[string][]ColumnInfo // 校验用的表结构缓存
+
+	nanPolicy NaNPolicy                     // 浮点 NaN/Inf 的处理方式
+	encoders  map[reflect.Type]ValueEncoder // 自定义类型的编码函数
+
+	primaryKeys map[string]string // 表 -> 复合主键列名（"" 表示没有），供更新与删除使用
+	keyOwners   map[string]string // 子表 -> 所属超级表（普通表为其自身），避免每次查询 ins_tables
+
+	evolutions map[string]*SchemaEvolution // 超级表 -> 自动加列配置
+	evolveMu   sync.Mutex                  // 串行化自动加列
+
+	restCfg    *taosRestful.Config // 解析后的 DSN，用于访问 taosAdapter 的 HTTP 接口
+	httpClient *http.Client
 }
 
 // NewClient 通过 REST DSN 建立连接，例如：root:pass@http(127.0.0.1:6041)/
@@ -35,7 +63,12 @@ func NewClient(dsn string) (*Client, error) {
 		db.Close()
 		return nil, err
 	}
-	return &Client{DB: db}, nil
+	cfg, err := taosRestful.ParseDSN(dsn)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Client{DB: db, restCfg: cfg}, nil
 }
 
 func (c *Client) Close() error {
@@ -55,43 +88,88 @@ func (c *Client) CreateDatabaseIfNotExists(dbName string) error {
 	return err
 }
 
-// UseDatabase 切换数据库
+// UseDatabase 切换数据库，成功后清空子表缓存与表结构缓存
 func (c *Client) UseDatabase(dbName string) error {
 	name, err := sanitizeIdent(dbName)
 	if err != nil {
 		return err
 	}
-	_, err = c.DB.Exec("USE " + name)
-	return err
+	if _, err = c.DB.Exec("USE " + name); err != nil {
+		return err
+	}
+	if sc := c.subTableCache(); sc != nil {
+		sc.reset()
+	}
+	c.InvalidateSchema("")
+	return nil
 }
 
 // CreateStable 创建超级表（幂等）。自动添加 ts TIMESTAMP
-func (c *Client) CreateStable(stable string, columns []ColumnDef, tagColumns []ColumnDef) error {
+// opts 可选，最多一个：COMMENT、MAX_DELAY、ROLLUP
+func (c *Client) CreateStable(stable string, columns []ColumnDef, tagColumns []ColumnDef, opts ...TableOptions) error {
 	st, err := sanitizeIdent(stable)
 	if err != nil {
 		return err
 	}
+	sqlStr, err := buildCreateStableSQL(st, columns, tagColumns, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = c.DB.Exec(sqlStr)
+	return err
+}
+
+// buildCreateStableSQL 生成 CREATE STABLE 语句，st 需由调用方校验
+func buildCreateStableSQL(st string, columns []ColumnDef, tagColumns []ColumnDef, opts ...TableOptions) (string, error) {
 	if len(columns) == 0 {
-		return errors.New("columns 不能为空")
+		return "", errors.New("columns 不能为空")
+	}
+	if err := validateJSONTags(columns, tagColumns); err != nil {
+		return "", err
 	}
-	// 字段定义
+	// 字段定义：复合主键列紧随 ts
 	fieldDefs := []string{"ts TIMESTAMP"}
+	var keyDef string
 	for _, col := range columns {
 		name, err := sanitizeIdent(col.Name)
 		if err != nil {
-			return err
+			return "", err
+		}
+		colOpts, err := columnOptions(col)
+		if err != nil {
+			return "", err
 		}
 		if strings.EqualFold(name, "ts") {
+			fieldDefs[0] = "ts TIMESTAMP" + colOpts
 			continue
 		}
-		fieldDefs = append(fieldDefs, fmt.Sprintf("%s %s", name, col.Type))
+		if col.PrimaryKey {
+			if keyDef != "" {
+				return "", errors.New("最多只能有一个复合主键列")
+			}
+			if !isPrimaryKeyType(col.Type) {
+				return "", fmt.Errorf("复合主键列 %s 的类型不支持: %s", name, col.Type)
+			}
+			keyDef = fmt.Sprintf("%s %s PRIMARY KEY%s", name, col.Type, colOpts)
+			continue
+		}
+		fieldDefs = append(fieldDefs, fmt.Sprintf("%s %s%s", name, col.Type, colOpts))
+	}
+	if keyDef != "" {
+		fieldDefs = append([]string{fieldDefs[0], keyDef}, fieldDefs[1:]...)
 	}
 	// TAG 定义
 	tagDefs := make([]string, 0, len(tagColumns))
 	for _, tag := range tagColumns {
 		name, err := sanitizeIdent(tag.Name)
 		if err != nil {
-			return err
+			return "", err
+		}
+		if tag.PrimaryKey {
+			return "", fmt.Errorf("TAG 列 %s 不能作为复合主键", name)
+		}
+		if tag.Encode != "" || tag.Compress != "" || tag.Level != "" {
+			return "", fmt.Errorf("TAG 列 %s 不支持压缩选项", name)
 		}
 		tagDefs = append(tagDefs, fmt.Sprintf("%s %s", name, tag.Type))
 	}
@@ -99,8 +177,24 @@ func (c *Client) CreateStable(stable string, columns []ColumnDef, tagColumns []C
 	if len(tagDefs) > 0 {
 		sqlStr += " TAGS (" + strings.Join(tagDefs, ", ") + ")"
 	}
-	_, err = c.DB.Exec(sqlStr)
-	return err
+	optClause, err := buildTableOptions(opts, true)
+	if err != nil {
+		return "", err
+	}
+	return sqlStr + optClause, nil
+}
+
+// isPrimaryKeyType 判断类型能否作为复合主键列
+func isPrimaryKeyType(typ string) bool {
+	t := strings.ToUpper(strings.Join(strings.Fields(typ), " "))
+	if i := strings.Index(t, "("); i >= 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	switch t {
+	case "INT", "INT UNSIGNED", "BIGINT", "BIGINT UNSIGNED", "VARCHAR", "BINARY":
+		return true
+	}
+	return false
 }
 
 // AddColumnToStable 为超级表增加列（采集字段）。此操作会自动应用到所有子表。
@@ -113,11 +207,71 @@ func (c *Client) AddColumnToStable(stable string, col ColumnDef) error {
 	if err != nil {
 		return err
 	}
-	sqlStr := fmt.Sprintf("ALTER STABLE %s ADD COLUMN %s %s", st, colName, col.Type)
+	if col.PrimaryKey {
+		return errors.New("复合主键列只能在建表时定义")
+	}
+	colOpts, err := columnOptions(col)
+	if err != nil {
+		return err
+	}
+	sqlStr := fmt.Sprintf("ALTER STABLE %s ADD COLUMN %s %s%s", st, colName, col.Type, colOpts)
 	_, err = c.DB.Exec(sqlStr)
+	c.InvalidateSchema("") // 子表结构随之变化
+	return err
+}
+
+// AlterColumnCompression 修改超级表（或普通表）某列的压缩选项（TDengine 3.3+）：
+// ALTER STABLE ... MODIFY COLUMN ... ENCODE/COMPRESS/LEVEL，仅使用 col 的 Name 与非空的 Encode、Compress、Level
+func (c *Client) AlterColumnCompression(stable string, col ColumnDef) error {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return err
+	}
+	colName, err := sanitizeIdent(col.Name)
+	if err != nil {
+		return err
+	}
+	colOpts, err := columnOptions(col)
+	if err != nil {
+		return err
+	}
+	if colOpts == "" {
+		return errors.New("ENCODE、COMPRESS、LEVEL 至少指定一项")
+	}
+	_, err = c.DB.Exec(fmt.Sprintf("ALTER STABLE %s MODIFY COLUMN %s%s", st, colName, colOpts))
 	return err
 }
 
+// GetColumnCompression 获取各普通列（含 ts，不含 TAG）当前的压缩设置
+// 旧版本服务端的 DESCRIBE 不返回压缩设置，此时 Encode/Compress/Level 为空
+func (c *Client) GetColumnCompression(stable string) ([]ColumnDef, error) {
+	infos, err := c.DescribeStable(stable)
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]ColumnDef, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsTag {
+			defs = append(defs, infoColumnDef(info))
+		}
+	}
+	return defs, nil
+}
+
+// infoColumnDef 将 DESCRIBE 结果还原为列定义；无法识别的压缩设置被忽略，以免生成的 DDL 无法执行
+func infoColumnDef(info ColumnInfo) ColumnDef {
+	def := ColumnDef{Name: info.Name, Type: columnTypeDDL(info), PrimaryKey: info.PrimaryKey}
+	if info.IsTag {
+		return def
+	}
+	withOpts := def
+	withOpts.Encode, withOpts.Compress, withOpts.Level = info.Encode, info.Compress, info.Level
+	if _, err := columnOptions(withOpts); err != nil {
+		return def
+	}
+	return withOpts
+}
+
 // GetStableColumns 获取超级表的所有列名（包含 TAGS）
 // 注意：基于 DESCRIBE 语句实现，返回顺序可能与定义顺序一致
 func (c *Client) GetStableColumns(stable string) ([]string, error) {
@@ -151,8 +305,73 @@ func (c *Client) GetStableColumns(stable string) ([]string, error) {
 	return cols, nil
 }
 
+// ColumnInfo 描述超级表/表中的一列（DESCRIBE 结果）
+type ColumnInfo struct {
+	Name       string
+	Type       string
+	Length     int
+	IsTag      bool
+	PrimaryKey bool // 复合主键列（不含 ts）
+	Note       string
+	Encode     string // 3.3 起 DESCRIBE 返回的列级压缩设置，TAG 列与旧版本为空
+	Compress   string
+	Level      string
+}
+
+// DescribeStable 获取超级表（或普通表）的列定义，按 DESCRIBE 返回顺序排列
+// 兼容不同版本 DESCRIBE 返回的列数（3.3 起额外返回 encode/compress/level）
+func (c *Client) DescribeStable(stable string) ([]ColumnInfo, error) {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return nil, err
+	}
+	return c.describe(st)
+}
+
+// describe 执行 DESCRIBE，name 需由调用方校验（可为 db.table 形式）
+func (c *Client) describe(name string) ([]ColumnInfo, error) {
+	rows, err := c.DB.Query("DESCRIBE " + name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) < 4 {
+		return nil, fmt.Errorf("DESCRIBE 返回列数异常: %d", len(names))
+	}
+	var infos []ColumnInfo
+	for rows.Next() {
+		vals := make([]interface{}, len(names))
+		scans := make([]interface{}, len(names))
+		for i := range vals {
+			scans[i] = &vals[i]
+		}
+		if err := rows.Scan(scans...); err != nil {
+			return nil, err
+		}
+		info := ColumnInfo{
+			Name: asString(vals[0]),
+			Type: asString(vals[1]),
+			Note: asString(vals[3]),
+		}
+		fmt.Sscanf(asString(vals[2]), "%d", &info.Length)
+		if len(vals) >= 7 {
+			info.Encode, info.Compress, info.Level = asString(vals[4]), asString(vals[5]), asString(vals[6])
+		}
+		info.IsTag = strings.EqualFold(strings.TrimSpace(info.Note), "TAG")
+		// 3.3 起复合主键列的 note 为 COMPOSITE KEY（部分版本为 PRIMARY KEY），首列 ts 除外
+		info.PrimaryKey = len(infos) > 0 && !info.IsTag && strings.HasSuffix(strings.ToUpper(strings.TrimSpace(info.Note)), " KEY")
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
 // EnsureSubTable 基于超级表自动创建子表（带 TAGS 值）
-func (c *Client) EnsureSubTable(sub string, stable string, tagValues []interface{}) error {
+// opts 可选，最多一个：TTL、COMMENT
+func (c *Client) EnsureSubTable(sub string, stable string, tagValues []interface{}, opts ...TableOptions) error {
 	subName, err := sanitizeIdent(sub)
 	if err != nil {
 		return err
@@ -161,110 +380,122 @@ func (c *Client) EnsureSubTable(sub string, stable string, tagValues []interface
 	if err != nil {
 		return err
 	}
-	vals := make([]string, 0, len(tagValues))
-	for _, v := range tagValues {
-		fv, err := formatValue(v)
-		if err != nil {
-			return err
-		}
-		vals = append(vals, fv)
+	tagList, err := c.codec().formatTagValues(tagValues)
+	if err != nil {
+		return err
 	}
-	sqlStr := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s USING %s TAGS (%s)", subName, st, strings.Join(vals, ", "))
-	_, err = c.DB.Exec(sqlStr)
-	return err
+	optClause, err := buildTableOptions(opts, false)
+	if err != nil {
+		return err
+	}
+	sc := c.subTableCache()
+	if sc != nil && sc.hit(subName, st, tagList) {
+		return nil
+	}
+	sqlStr := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s USING %s TAGS (%s)%s", subName, st, tagList, optClause)
+	if _, err = c.DB.Exec(sqlStr); err != nil {
+		return err
+	}
+	if sc != nil {
+		sc.applied(subName, st, tagList)
+	}
+	return nil
 }
 
-// Insert 插入一行。若未提供 ts，则使用 NOW()
+// Insert 插入一行。若未提供 ts，按 SetTimestampPolicy 的策略分配（默认 NOW()）
 func (c *Client) Insert(table string, row map[string]interface{}) error {
 	tbl, err := sanitizeIdent(table)
 	if err != nil {
 		return err
 	}
-	cols := []string{"ts"}
-	vals := []string{"NOW()"}
-	if v, ok := row["ts"]; ok {
-		fv, err := formatValue(v)
-		if err != nil {
-			return err
-		}
-		vals[0] = fv
+	tables, err := c.validateTables([]TableRows{{Table: tbl, Rows: []map[string]interface{}{row}}})
+	if err != nil {
+		return err
 	}
-	for k, v := range row {
-		if strings.EqualFold(k, "ts") {
-			continue
-		}
-		col, err := sanitizeIdent(k)
-		if err != nil {
-			return err
+	body, err := buildInsertBody(tables[0].Rows, c.tsFillerFor(tbl), c.codec())
+	if err != nil {
+		return err
+	}
+	sqlStr := "INSERT INTO " + tbl + " " + body
+	_, err = c.DB.Exec(sqlStr)
+	if err != nil && isUnknownColumn(err) && c.hasSchemaEvolution() {
+		evolved, eerr := c.evolveSchema(tbl, "", tables[0].Rows)
+		if eerr != nil {
+			return fmt.Errorf("%w（自动加列失败: %v）", err, eerr)
 		}
-		fv, err := formatValue(v)
-		if err != nil {
-			return err
+		if evolved {
+			_, err = c.DB.Exec(sqlStr)
 		}
-		cols = append(cols, col)
-		vals = append(vals, fv)
 	}
-	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tbl, strings.Join(cols, ", "), strings.Join(vals, ", "))
-	_, err = c.DB.Exec(sqlStr)
-	return err
+	return c.invalidateOnMissing(tbl, err)
 }
 
-// BatchInsert 批量插入多行（简单拼接）。
+// BatchInsert 批量插入多行。超过 SQL 长度上限时自动拆分为多条语句
 func (c *Client) BatchInsert(table string, rows []map[string]interface{}) error {
-	if len(rows) == 0 {
-		return nil
-	}
-	tbl, err := sanitizeIdent(table)
+	_, err := c.BatchInsertWithResult(table, rows)
+	return err
+}
+
+// buildInsertBody 生成 "(列...) VALUES (...) (...)" 片段
+func buildInsertBody(rows []map[string]interface{}, fill tsFiller, vc *valueCodec) (string, error) {
+	cols, groups, err := buildInsertValues(rows, fill, vc)
 	if err != nil {
-		return err
+		return "", err
 	}
+	return fmt.Sprintf("(%s) VALUES %s", strings.Join(cols, ", "), strings.Join(groups, " ")), nil
+}
+
+// buildInsertValues 生成列清单与每行的 "(...)" 值组
+// 列为所有行键的并集，ts 固定在首位；未提供 ts 的行由 fill 生成（为 nil 时使用 NOW()），缺失的列为 NULL
+func buildInsertValues(rows []map[string]interface{}, fill tsFiller, vc *valueCodec) ([]string, []string, error) {
 	// 收集列集合
-	colSet := map[string]struct{}{"ts": {}}
+	colSet := map[string]struct{}{}
+	cols := []string{"ts"}
 	for _, r := range rows {
 		for k := range r {
-			colSet[k] = struct{}{}
-		}
-	}
-	cols := make([]string, 0, len(colSet))
-	for k := range colSet {
-		cols = append(cols, k)
-	}
-	// 保证 ts 在首位
-	sortCols := []string{"ts"}
-	for _, c := range cols {
-		if c != "ts" {
-			sortCols = append(sortCols, c)
+			if strings.EqualFold(k, "ts") {
+				continue
+			}
+			col, err := sanitizeIdent(k)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, ok := colSet[col]; !ok {
+				colSet[col] = struct{}{}
+				cols = append(cols, col)
+			}
 		}
 	}
 	// 构造 VALUES
 	valGroups := make([]string, 0, len(rows))
-	for _, r := range rows {
-		vals := make([]string, 0, len(sortCols))
-		for _, c := range sortCols {
-			if c == "ts" {
-				if v, ok := r["ts"]; ok {
-					fv, err := formatValue(v)
+	for n, r := range rows {
+		vals := make([]string, 0, len(cols))
+		for i, col := range cols {
+			v, ok := r[col]
+			if i == 0 {
+				v, ok = lookupTag(r, "ts")
+				if !ok {
+					if fill == nil {
+						vals = append(vals, "NOW()")
+						continue
+					}
+					lit, err := fill(n)
 					if err != nil {
-						return err
+						return nil, nil, err
 					}
-					vals = append(vals, fv)
-				} else {
-					vals = append(vals, "NOW()")
+					vals = append(vals, lit)
+					continue
 				}
-				continue
 			}
-			v := r[c]
-			fv, err := formatValue(v)
+			fv, err := vc.formatValue(v)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 			vals = append(vals, fv)
 		}
 		valGroups = append(valGroups, "("+strings.Join(vals, ", ")+")")
 	}
-	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", tbl, strings.Join(sortCols, ", "), strings.Join(valGroups, " "))
-	_, err = c.DB.Exec(sqlStr)
-	return err
+	return cols, valGroups, nil
 }
 
 // Query 以筛选条件查询，返回行列表（map）
@@ -285,7 +516,7 @@ func (c *Client) Query(table string, columns []string, f Filter) ([]map[string]i
 		}
 		cols = strings.Join(parts, ", ")
 	}
-	where, err := f.buildWhere()
+	where, err := f.buildWhere(c.codec())
 	if err != nil {
 		return nil, err
 	}
@@ -299,60 +530,14 @@ func (c *Client) Query(table string, columns []string, f Filter) ([]map[string]i
 		return nil, err
 	}
 	defer rows.Close()
-	result := make([]map[string]interface{}, 0)
-	for rows.Next() {
-		cols, err := rows.Columns()
-		if err != nil {
-			return nil, err
-		}
-		vals := make([]interface{}, len(cols))
-		scans := make([]interface{}, len(cols))
-		for i := range vals {
-			scans[i] = &vals[i]
-		}
-		if err := rows.Scan(scans...); err != nil {
-			return nil, err
-		}
-		row := make(map[string]interface{}, len(cols))
-		for i, col := range cols {
-			row[col] = vals[i]
-		}
-		result = append(result, row)
-	}
-	return result, rows.Err()
+	return scanRowMaps(rows, nil)
 }
 
-// Update 执行更新（注意：不同 TDengine 版本对 UPDATE 支持不同）
+// Update 执行更新。TDengine 3 不支持 UPDATE 语句，通过 UpdateWhere 以相同时间戳重写 set 中的列实现
+// 与 Delete 一样要求筛选条件非空
 func (c *Client) Update(table string, set map[string]interface{}, f Filter) (int64, error) {
-	tbl, err := sanitizeIdent(table)
-	if err != nil {
-		return 0, err
-	}
-	if len(set) == 0 {
-		return 0, errors.New("set 不能为空")
-	}
-	pairs := make([]string, 0, len(set))
-	for k, v := range set {
-		col, err := sanitizeIdent(k)
-		if err != nil {
-			return 0, err
-		}
-		fv, err := formatValue(v)
-		if err != nil {
-			return 0, err
-		}
-		pairs = append(pairs, fmt.Sprintf("%s=%s", col, fv))
-	}
-	where, err := f.buildWhere()
-	if err != nil {
-		return 0, err
-	}
-	sqlStr := fmt.Sprintf("UPDATE %s SET %s %s", tbl, strings.Join(pairs, ", "), where)
-	res, err := c.DB.Exec(sqlStr)
-	if err != nil {
-		return 0, err
-	}
-	return res.RowsAffected()
+	report, err := c.UpdateWhere(table, set, f, UpdateOptions{})
+	return int64(report.Written), err
 }
 
 // Delete 删除（注意：不同 TDengine 版本对 DELETE 支持不同）
@@ -361,13 +546,16 @@ func (c *Client) Delete(table string, f Filter) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	where, err := f.buildWhere()
+	where, err := f.buildWhere(c.codec())
 	if err != nil {
 		return 0, err
 	}
 	if strings.TrimSpace(where) == "" {
 		return 0, errors.New("危险操作：不允许无 WHERE 的删除")
 	}
+	if err := c.checkDeleteKey(tbl, f); err != nil {
+		return 0, err
+	}
 	sqlStr := fmt.Sprintf("DELETE FROM %s %s", tbl, where)
 	res, err := c.DB.Exec(sqlStr)
 	if err != nil {
@@ -392,7 +580,7 @@ func (c *Client) QueryAggregateAcrossStable(stable string, aggExpr string, f Fil
 	if err != nil {
 		return nil, err
 	}
-	where, err := f.buildWhere()
+	where, err := f.buildWhere(c.codec())
 	if err != nil {
 		return nil, err
 	}
@@ -411,27 +599,7 @@ func (c *Client) QueryAggregateAcrossStable(stable string, aggExpr string, f Fil
 		return nil, err
 	}
 	defer rows.Close()
-	result := make([]map[string]interface{}, 0)
-	for rows.Next() {
-		cols, err := rows.Columns()
-		if err != nil {
-			return nil, err
-		}
-		vals := make([]interface{}, len(cols))
-		scans := make([]interface{}, len(cols))
-		for i := range vals {
-			scans[i] = &vals[i]
-		}
-		if err := rows.Scan(scans...); err != nil {
-			return nil, err
-		}
-		row := make(map[string]interface{}, len(cols))
-		for i, col := range cols {
-			row[col] = vals[i]
-		}
-		result = append(result, row)
-	}
-	return result, rows.Err()
+	return scanRowMaps(rows, nil)
 }
 
 // QueryDownsampleWithFill 对单表或超级表做降采样并插值
@@ -441,7 +609,7 @@ func (c *Client) QueryDownsampleWithFill(stableOrTable string, selectExpr string
 	if err != nil {
 		return nil, err
 	}
-	where, err := f.buildWhere()
+	where, err := f.buildWhere(c.codec())
 	if err != nil {
 		return nil, err
 	}
@@ -456,27 +624,7 @@ func (c *Client) QueryDownsampleWithFill(stableOrTable string, selectExpr string
 		return nil, err
 	}
 	defer rows.Close()
-	result := make([]map[string]interface{}, 0)
-	for rows.Next() {
-		cols, err := rows.Columns()
-		if err != nil {
-			return nil, err
-		}
-		vals := make([]interface{}, len(cols))
-		scans := make([]interface{}, len(cols))
-		for i := range vals {
-			scans[i] = &vals[i]
-		}
-		if err := rows.Scan(scans...); err != nil {
-			return nil, err
-		}
-		row := make(map[string]interface{}, len(cols))
-		for i, col := range cols {
-			row[col] = vals[i]
-		}
-		result = append(result, row)
-	}
-	return result, rows.Err()
+	return scanRowMaps(rows, nil)
 }
 
 // AsyncQuery 异步查询：返回结果通道、错误通道与取消函数
@@ -494,35 +642,12 @@ func (c *Client) AsyncQuery(sqlStr string) (<-chan []map[string]interface{}, <-c
 			return
 		}
 		defer rows.Close()
-		result := make([]map[string]interface{}, 0)
-		for rows.Next() {
-			select {
-			case <-cancel:
-				return
-			default:
-			}
-			cols, err := rows.Columns()
-			if err != nil {
-				errCh <- err
-				return
-			}
-			vals := make([]interface{}, len(cols))
-			scans := make([]interface{}, len(cols))
-			for i := range vals {
-				scans[i] = &vals[i]
-			}
-			if err := rows.Scan(scans...); err != nil {
-				errCh <- err
-				return
-			}
-			row := make(map[string]interface{}, len(cols))
-			for i, col := range cols {
-				row[col] = vals[i]
-			}
-			result = append(result, row)
+		result, err := scanRowMaps(rows, cancel)
+		if err == errQueryCanceled {
+			return
 		}
-		if rows.Err() != nil {
-			errCh <- rows.Err()
+		if err != nil {
+			errCh <- err
 			return
 		}
 		resCh <- result
@@ -530,6 +655,52 @@ func (c *Client) AsyncQuery(sqlStr string) (<-chan []map[string]interface{}, <-c
 	return resCh, errCh, cancelFn
 }
 
+// errQueryCanceled 表示异步查询被取消
+var errQueryCanceled = errors.New("查询已取消")
+
+// scanRowMaps 将结果集逐行转换为 map，JSON 类型列解码为 map[string]interface{}
+// cancel 非空时在每行读取前检查是否已取消
+func scanRowMaps(rows *sql.Rows, cancel <-chan struct{}) ([]map[string]interface{}, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	jsonCols := make([]bool, len(cols))
+	if types, err := rows.ColumnTypes(); err == nil {
+		for i, ct := range types {
+			jsonCols[i] = isJSONType(ct.DatabaseTypeName())
+		}
+	}
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		if cancel != nil {
+			select {
+			case <-cancel:
+				return nil, errQueryCanceled
+			default:
+			}
+		}
+		vals := make([]interface{}, len(cols))
+		scans := make([]interface{}, len(cols))
+		for i := range vals {
+			scans[i] = &vals[i]
+		}
+		if err := rows.Scan(scans...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			if jsonCols[i] {
+				row[col] = decodeJSONValue(vals[i])
+				continue
+			}
+			row[col] = vals[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
 // CreateContinuousQuery 封装创建连续查询（CQ）语句执行
 // 传入完整 SQL，如：CREATE TABLE target AS SELECT ... INTERVAL(60s)
 func (c *Client) CreateContinuousQuery(sqlStr string) error {
@@ -620,8 +791,8 @@ func (c *Client) UseDatabaseMsg(dbName string) (string, error) {
 }
 
 // CreateStableMsg 创建超级表并返回提示
-func (c *Client) CreateStableMsg(stable string, columns []ColumnDef, tagColumns []ColumnDef) (string, error) {
-	if err := c.CreateStable(stable, columns, tagColumns); err != nil {
+func (c *Client) CreateStableMsg(stable string, columns []ColumnDef, tagColumns []ColumnDef, opts ...TableOptions) (string, error) {
+	if err := c.CreateStable(stable, columns, tagColumns, opts...); err != nil {
 		return "", fmt.Errorf("CreateStable %s failed: %w", stable, err)
 	}
 	return fmt.Sprintf("超级表已创建/存在: %s", stable), nil
@@ -635,6 +806,14 @@ func (c *Client) AddColumnToStableMsg(stable string, col ColumnDef) (string, err
 	return fmt.Sprintf("超级表 %s 已增加列: %s", stable, col.Name), nil
 }
 
+// AlterColumnCompressionMsg 修改列压缩选项并返回提示
+func (c *Client) AlterColumnCompressionMsg(stable string, col ColumnDef) (string, error) {
+	if err := c.AlterColumnCompression(stable, col); err != nil {
+		return "", fmt.Errorf("AlterColumnCompression %s.%s failed: %w", stable, col.Name, err)
+	}
+	return fmt.Sprintf("超级表 %s 列 %s 的压缩选项已修改", stable, col.Name), nil
+}
+
 // GetStableColumnsMsg 获取超级表列名并返回提示
 func (c *Client) GetStableColumnsMsg(stable string) ([]string, string, error) {
 	cols, err := c.GetStableColumns(stable)
@@ -645,8 +824,8 @@ func (c *Client) GetStableColumnsMsg(stable string) ([]string, string, error) {
 }
 
 // EnsureSubTableMsg 创建子表并返回提示
-func (c *Client) EnsureSubTableMsg(sub string, stable string, tagValues []interface{}) (string, error) {
-	if err := c.EnsureSubTable(sub, stable, tagValues); err != nil {
+func (c *Client) EnsureSubTableMsg(sub string, stable string, tagValues []interface{}, opts ...TableOptions) (string, error) {
+	if err := c.EnsureSubTable(sub, stable, tagValues, opts...); err != nil {
 		return "", fmt.Errorf("EnsureSubTable %s using %s failed: %w", sub, stable, err)
 	}
 	return fmt.Sprintf("子表已创建/存在: %s (USING %s)", sub, stable), nil
@@ -0,0 +1,369 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: CSV 批量导入（按超级表结构转换、自动建子表、分批写入）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportSpec CSV 导入配置
+type ImportSpec struct {
+	Stable string // 目标超级表（或普通表），用于按结构转换类型，必填
+	// Table 固定写入的表名；为空时取 TableColumn 列的值，再为空时按 SetSubTableNamer 注册的命名策略推导
+	Table       string
+	TableColumn string // CSV 中子表名所在的列
+	// Columns CSV 表头 -> 列/TAG 名的映射；未列出的表头按同名（不区分大小写）匹配，映射为 "" 表示忽略该列
+	Columns    map[string]string
+	TimeColumn string         // CSV 中时间戳所在的表头，默认 "ts"
+	TimeLayout string         // 时间格式，默认 "2006-01-02 15:04:05"；也可为 unix、unix_ms、unix_us、unix_ns
+	Location   *time.Location // 时间不含时区时使用的时区，默认 time.Local
+	Comma      rune           // 分隔符，默认 ','
+	BatchSize  int            // 每批写入行数，默认 1000
+	Progress   func(ImportProgress)
+}
+
+// ImportProgress 导入进度，每批写入后回调
+type ImportProgress struct {
+	Read    int // 已读取的数据行数
+	Written int // 已写入的行数
+	Failed  int // 解析或写入失败的行数
+}
+
+// ImportRowError 单行导入失败的原因，Line 为 CSV 中的行号（表头为第 1 行）
+type ImportRowError struct {
+	Line int
+	Err  error
+}
+
+// ImportReport 导入结果
+type ImportReport struct {
+	ImportProgress
+	Errors []ImportRowError
+}
+
+// csvField 表头对应的目标
+type csvField struct {
+	col   ColumnInfo
+	isTS  bool
+	isTbl bool
+	skip  bool
+}
+
+// ImportCSV 从 CSV 导入数据：首行为表头，按超级表结构转换值，子表不存在时自动创建（USING ... TAGS）
+// 单行的解析或写入失败记录在报告中并继续导入；读取失败、表头不匹配等错误直接返回
+func (c *Client) ImportCSV(r io.Reader, spec ImportSpec) (ImportReport, error) {
+	var report ImportReport
+	st, err := sanitizeIdent(spec.Stable)
+	if err != nil {
+		return report, err
+	}
+	infos, err := c.DescribeStable(st)
+	if err != nil {
+		return report, err
+	}
+	if spec.TimeColumn == "" {
+		spec.TimeColumn = "ts"
+	}
+	if spec.Location == nil {
+		spec.Location = time.Local
+	}
+	if spec.BatchSize <= 0 {
+		spec.BatchSize = 1000
+	}
+	cr := csv.NewReader(r)
+	if spec.Comma != 0 {
+		cr.Comma = spec.Comma
+	}
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return report, fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	header = append([]string(nil), header...) // ReuseRecord 会覆盖首行
+	fields, err := mapCSVHeader(header, infos, spec)
+	if err != nil {
+		return report, err
+	}
+	var tagOrder []string
+	for _, info := range infos {
+		if info.IsTag {
+			tagOrder = append(tagOrder, info.Name)
+		}
+	}
+
+	c.mu.RLock()
+	opts := c.batchOpts
+	c.mu.RUnlock()
+	opts.IsolateFailures = true // 逐行报告写入错误
+
+	var buf []WriteRow
+	var lines []int
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		tables, order := groupWriteRows(buf)
+		res, err := c.batchInsertTables(tables, opts)
+		report.Written += res.Written
+		var be *BatchError
+		switch {
+		case errors.As(err, &be):
+			for _, re := range be.Rows {
+				report.Errors = append(report.Errors, ImportRowError{Line: lines[order[re.Index]], Err: re.Err})
+			}
+			report.Failed += len(be.Rows)
+		case err != nil:
+			// 拼装语句失败等整体错误，整批计为失败
+			for _, l := range lines {
+				report.Errors = append(report.Errors, ImportRowError{Line: l, Err: err})
+			}
+			report.Failed += len(buf)
+		}
+		buf, lines = buf[:0], lines[:0]
+		if spec.Progress != nil {
+			spec.Progress(report.ImportProgress)
+		}
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return report, err
+			}
+			report.Read++
+			report.Failed++
+			report.Errors = append(report.Errors, ImportRowError{Line: pe.StartLine, Err: err})
+			continue
+		}
+		report.Read++
+		line, _ := cr.FieldPos(0)
+		wr, err := c.csvWriteRow(rec, header, fields, tagOrder, st, spec)
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportRowError{Line: line, Err: err})
+			continue
+		}
+		buf = append(buf, wr)
+		lines = append(lines, line)
+		if len(buf) >= spec.BatchSize {
+			flush()
+		}
+	}
+	flush()
+	return report, nil
+}
+
+// mapCSVHeader 将表头映射为超级表的列或 TAG
+func mapCSVHeader(header []string, infos []ColumnInfo, spec ImportSpec) ([]csvField, error) {
+	fields := make([]csvField, len(header))
+	hasTS := false
+	for i, h := range header {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		header[i] = h
+		if spec.TableColumn != "" && strings.EqualFold(h, spec.TableColumn) {
+			fields[i].isTbl = true
+			continue
+		}
+		target := h
+		if mapped, ok := spec.Columns[h]; ok {
+			if mapped == "" {
+				fields[i].skip = true
+				continue
+			}
+			target = mapped
+		}
+		if strings.EqualFold(h, spec.TimeColumn) {
+			target = "ts"
+		}
+		found := false
+		for _, info := range infos {
+			if strings.EqualFold(info.Name, target) {
+				fields[i].col = info
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("CSV 列 %s 未匹配到超级表 %s 的列或 TAG", h, spec.Stable)
+		}
+		if strings.EqualFold(target, "ts") {
+			fields[i].isTS = true
+			hasTS = true
+		}
+	}
+	if !hasTS {
+		return nil, fmt.Errorf("CSV 缺少时间戳列 %s", spec.TimeColumn)
+	}
+	return fields, nil
+}
+
+// csvWriteRow 将一条 CSV 记录转换为待写入的行
+func (c *Client) csvWriteRow(rec []string, header []string, fields []csvField, tagOrder []string, st string, spec ImportSpec) (WriteRow, error) {
+	if len(rec) != len(fields) {
+		return WriteRow{}, fmt.Errorf("字段数 %d 与表头 %d 不一致", len(rec), len(fields))
+	}
+	wr := WriteRow{Table: spec.Table, Row: map[string]interface{}{}}
+	tags := map[string]interface{}{}
+	for i, f := range fields {
+		raw := rec[i]
+		switch {
+		case f.skip:
+			continue
+		case f.isTbl:
+			if wr.Table == "" {
+				wr.Table = strings.TrimSpace(raw)
+			}
+			continue
+		}
+		var v interface{}
+		var err error
+		if f.isTS || strings.EqualFold(f.col.Type, "TIMESTAMP") {
+			if raw != "" {
+				v, err = parseCSVTime(raw, spec.TimeLayout, spec.Location)
+			}
+		} else {
+			v, err = parseColumnValue(raw, f.col)
+		}
+		if err != nil {
+			return WriteRow{}, fmt.Errorf("列 %s: %w", header[i], err)
+		}
+		if f.col.IsTag {
+			tags[f.col.Name] = v
+		} else if v != nil {
+			wr.Row[f.col.Name] = v
+		}
+	}
+	if _, ok := wr.Row["ts"]; !ok {
+		return WriteRow{}, fmt.Errorf("时间戳为空")
+	}
+	if len(tagOrder) > 0 && len(tags) > 0 {
+		if wr.Table == "" {
+			name, err := c.SubTableName(st, tags)
+			if err != nil {
+				return WriteRow{}, err
+			}
+			wr.Table = name
+		}
+		wr.Stable = st
+		wr.Tags = make([]interface{}, len(tagOrder))
+		for i, t := range tagOrder {
+			wr.Tags[i], _ = lookupTag(tags, t)
+		}
+	}
+	if wr.Table == "" {
+		return WriteRow{}, fmt.Errorf("无法确定子表名")
+	}
+	if _, err := sanitizeIdent(wr.Table); err != nil {
+		return WriteRow{}, err
+	}
+	return wr, nil
+}
+
+// parseCSVTime 按布局解析时间戳，layout 为 unix/unix_ms/unix_us/unix_ns 时按整数时间戳解析
+// 结果转换为本地时区，与 formatValue 输出时间的方式一致
+func parseCSVTime(s, layout string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var unit time.Duration
+	switch layout {
+	case "":
+		layout = "2006-01-02 15:04:05"
+	case "unix":
+		unit = time.Second
+	case "unix_ms":
+		unit = time.Millisecond
+	case "unix_us":
+		unit = time.Microsecond
+	case "unix_ns":
+		unit = time.Nanosecond
+	}
+	if unit > 0 {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("无效的时间戳: %s", s)
+		}
+		return time.Unix(0, n*int64(unit)), nil
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的时间: %s", s)
+	}
+	return t.In(time.Local), nil
+}
+
+// parseColumnValue 按列类型将文本转换为 Go 值；空字符串视为 NULL（字符串类型同样）
+func parseColumnValue(s string, col ColumnInfo) (interface{}, error) {
+	if s == "" {
+		return nil, nil
+	}
+	typ := strings.ToUpper(col.Type)
+	var v interface{}
+	var err error
+	switch {
+	case typ == "BOOL":
+		v, err = strconv.ParseBool(strings.TrimSpace(s))
+	case strings.HasSuffix(typ, "INT UNSIGNED"):
+		v, err = strconv.ParseUint(strings.TrimSpace(s), 10, intTypeBits(typ))
+	case strings.HasSuffix(typ, "INT"):
+		v, err = strconv.ParseInt(strings.TrimSpace(s), 10, intTypeBits(typ))
+	case typ == "FLOAT":
+		v, err = strconv.ParseFloat(strings.TrimSpace(s), 32)
+	case typ == "DOUBLE":
+		v, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case typ == "JSON":
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("无效的 JSON: %s", s)
+		}
+		return json.RawMessage(s), nil
+	default: // BINARY / VARCHAR / NCHAR / VARBINARY / GEOMETRY
+		if err := checkStringLength(s, typ, col.Length); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法转换为 %s: %s", typ, s)
+	}
+	return v, nil
+}
+
+// checkStringLength 检查字符串是否超过列长度，NCHAR 按字符计长，其余按字节
+func checkStringLength(s string, typ string, length int) error {
+	n := len(s)
+	if typ == "NCHAR" {
+		n = len([]rune(s))
+	}
+	if length > 0 && n > length {
+		return fmt.Errorf("长度 %d 超过 %s(%d)", n, typ, length)
+	}
+	return nil
+}
+
+// intTypeBits 整数类型的位数
+func intTypeBits(typ string) int {
+	switch {
+	case strings.HasPrefix(typ, "TINYINT"):
+		return 8
+	case strings.HasPrefix(typ, "SMALLINT"):
+		return 16
+	case strings.HasPrefix(typ, "INT"):
+		return 32
+	}
+	return 64
+}
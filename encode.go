@@ -0,0 +1,217 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: Go 值到 SQL 字面量的编码（可注册自定义类型）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueEncoder 将自定义类型转换为可被编码的值（如 string、int64、float64、time.Time、nil）
+type ValueEncoder func(v interface{}) (interface{}, error)
+
+// NaNPolicy 浮点 NaN/Inf 的处理方式（SQL 中没有对应的字面量）
+type NaNPolicy int32
+
+const (
+	// NaNReject 返回错误（默认）
+	NaNReject NaNPolicy = iota
+	// NaNAsNull 写入 NULL
+	NaNAsNull
+)
+
+// valueCodec Client 的值编码配置：NaN/Inf 策略与自定义编码函数；nil 表示默认配置
+type valueCodec struct {
+	nanPolicy NaNPolicy
+	encoders  map[reflect.Type]ValueEncoder // 写时复制，取得后可不加锁读取
+}
+
+// RegisterValueEncoder 为 sample 的类型注册编码函数，优先于内置规则；enc 为 nil 时取消注册
+// 例如：cli.RegisterValueEncoder(decimal.Decimal{}, func(v interface{}) (interface{}, error) { return v.(decimal.Decimal).String(), nil })
+func (c *Client) RegisterValueEncoder(sample interface{}, enc ValueEncoder) {
+	t := reflect.TypeOf(sample)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	encoders := make(map[reflect.Type]ValueEncoder, len(c.encoders)+1)
+	for k, v := range c.encoders {
+		encoders[k] = v
+	}
+	if enc == nil {
+		delete(encoders, t)
+	} else {
+		encoders[t] = enc
+	}
+	c.encoders = encoders
+}
+
+// SetNaNPolicy 设置浮点 NaN/Inf 的处理方式
+func (c *Client) SetNaNPolicy(p NaNPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nanPolicy = p
+}
+
+// codec 返回当前的值编码配置
+func (c *Client) codec() *valueCodec {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &valueCodec{nanPolicy: c.nanPolicy, encoders: c.encoders}
+}
+
+func (vc *valueCodec) lookupEncoder(v interface{}) ValueEncoder {
+	if vc == nil {
+		return nil
+	}
+	return vc.encoders[reflect.TypeOf(v)]
+}
+
+// maxEncodeDepth 自定义编码、driver.Valuer 与指针的最大展开层数，防止循环
+const maxEncodeDepth = 8
+
+// VarBinary 按十六进制字面量写入 VARBINARY/GEOMETRY 列的字节串
+// 普通 []byte 按字符串编码；开启写入校验时，写入 VARBINARY/GEOMETRY 列的 []byte 会自动转换为 VarBinary
+type VarBinary []byte
+
+// formatValue 将 Go 值格式化为 TDengine SQL 值
+// 支持：基础类型及其命名类型、指针、[]byte（按字符串）、VarBinary（十六进制字面量）、json.RawMessage、
+// time.Time、time.Duration（纳秒整数，与 database/sql 一致）、driver.Valuer（含 sql.Null*）及注册的自定义类型
+// 使用默认配置，Client 写入与查询用户值时使用 valueCodec.formatValue
+func formatValue(v interface{}) (string, error) {
+	return (*valueCodec)(nil).formatValue(v)
+}
+
+// formatValue 按 Client 的 NaN/Inf 策略与自定义编码函数格式化值
+func (vc *valueCodec) formatValue(v interface{}) (string, error) {
+	return vc.encodeValue(v, 0)
+}
+
+func (vc *valueCodec) encodeValue(v interface{}, depth int) (string, error) {
+	if depth > maxEncodeDepth {
+		return "", fmt.Errorf("值编码层数过深: %T", v)
+	}
+	if v == nil {
+		return "NULL", nil
+	}
+	if enc := vc.lookupEncoder(v); enc != nil {
+		nv, err := enc(v)
+		if err != nil {
+			return "", err
+		}
+		return vc.encodeValue(nv, depth+1)
+	}
+	switch val := v.(type) {
+	case string:
+		return quoteString(val)
+	case time.Time:
+		return fmt.Sprintf("'%s'", val.Format("2006-01-02 15:04:05.000")), nil
+	case json.RawMessage:
+		if !json.Valid(val) {
+			return "", fmt.Errorf("非法 JSON: %s", string(val))
+		}
+		return quoteJSON(string(val)), nil
+	case VarBinary:
+		return hexLiteral(val), nil
+	case []byte:
+		return quoteString(string(val))
+	case bool:
+		if val {
+			return "1", nil
+		}
+		return "0", nil
+	case driver.Valuer:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "NULL", nil
+		}
+		dv, err := val.Value()
+		if err != nil {
+			return "", err
+		}
+		return vc.encodeValue(dv, depth+1)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return vc.encodeValue(rv.Elem().Interface(), depth+1)
+	case reflect.String:
+		return quoteString(rv.String())
+	case reflect.Bool:
+		return vc.encodeValue(rv.Bool(), depth)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return vc.formatFloat(rv.Float(), rv.Type().Bits())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return quoteString(string(rv.Bytes()))
+		}
+	}
+	return "", fmt.Errorf("不支持的值类型: %T", v)
+}
+
+// formatFloat 按 NaN/Inf 策略格式化浮点数
+func (vc *valueCodec) formatFloat(f float64, bits int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if vc != nil && vc.nanPolicy == NaNAsNull {
+			return "NULL", nil
+		}
+		return "", fmt.Errorf("不支持写入 NaN/Inf: %v", f)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits), nil
+}
+
+// quoteString 生成字符串字面量：单引号重复一次，反斜杠与 \n \r \t 使用反斜杠转义；
+// 其他控制字符无法在 SQL 中表示，返回错误
+func quoteString(s string) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('\'')
+	for _, ch := range s {
+		switch ch {
+		case '\'':
+			sb.WriteString("''")
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if ch < 0x20 || ch == 0x7f {
+				return "", fmt.Errorf("字符串包含控制字符 %U", ch)
+			}
+			sb.WriteRune(ch)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String(), nil
+}
+
+// quoteLike 生成 LIKE 模式字面量：只重复单引号，反斜杠原样保留，作为模式中的转义符交给服务端
+func quoteLike(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// hexLiteral 生成 VARBINARY/GEOMETRY 的十六进制字面量：'\x0102...'
+func hexLiteral(b []byte) string {
+	return `'\x` + hex.EncodeToString(b) + "'"
+}
@@ -0,0 +1,270 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 模式演进：写入未知列时自动为超级表添加列并重试
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaEvolution 超级表的自动加列配置
+type SchemaEvolution struct {
+	Allow      []string          // 允许自动添加的列名，不区分大小写，以 * 结尾表示前缀匹配；为空时允许全部
+	Types      map[string]string // 列名 -> 类型（如 "DOUBLE"、"VARCHAR(128)"），优先于按值推断
+	MaxColumns int               // 普通列（含 ts，不含 TAG）数量上限，默认 4096
+}
+
+// EnableSchemaEvolution 为超级表开启模式演进：写入因未知列失败时，
+// 按值推断类型（或使用 Types）调用 AddColumnToStable 添加列，然后重试一次写入
+func (c *Client) EnableSchemaEvolution(stable string, se SchemaEvolution) error {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return err
+	}
+	for name, typ := range se.Types {
+		if _, err := sanitizeIdent(name); err != nil {
+			return err
+		}
+		if typ == "" {
+			return fmt.Errorf("列 %s 的类型不能为空", name)
+		}
+	}
+	if se.MaxColumns <= 0 {
+		se.MaxColumns = 4096
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.evolutions == nil {
+		c.evolutions = make(map[string]*SchemaEvolution)
+	}
+	c.evolutions[strings.ToLower(st)] = &se
+	return nil
+}
+
+// DisableSchemaEvolution 关闭超级表的模式演进
+func (c *Client) DisableSchemaEvolution(stable string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.evolutions, strings.ToLower(stable))
+}
+
+// schemaEvolution 返回超级表的演进配置，未开启时返回 nil
+func (c *Client) schemaEvolution(stable string) *SchemaEvolution {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.evolutions[strings.ToLower(stable)]
+}
+
+// evolutionFor 返回表（或其所属超级表）的演进配置；stable 为空时查询子表所属的超级表
+func (c *Client) evolutionFor(table, stable string) *SchemaEvolution {
+	if !c.hasSchemaEvolution() {
+		return nil
+	}
+	if stable == "" {
+		stable, _ = c.stableOf(table)
+		if stable == "" {
+			return nil
+		}
+	}
+	return c.schemaEvolution(stable)
+}
+
+func (c *Client) hasSchemaEvolution() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.evolutions) > 0
+}
+
+// allows 判断列名是否在白名单中
+func (se *SchemaEvolution) allows(col string) bool {
+	if len(se.Allow) == 0 {
+		return true
+	}
+	for _, a := range se.Allow {
+		if strings.HasSuffix(a, "*") {
+			if strings.HasPrefix(strings.ToLower(col), strings.ToLower(strings.TrimSuffix(a, "*"))) {
+				return true
+			}
+		} else if strings.EqualFold(a, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// evolveSchema 为 rows 中超级表尚不存在的列执行 ALTER STABLE ADD COLUMN
+// 返回是否值得重试写入：超级表开启了演进且列已齐全（可能由其他写入者刚刚添加）
+// stable 为空时通过 information_schema 查询子表所属的超级表
+func (c *Client) evolveSchema(table, stable string, rows []map[string]interface{}) (bool, error) {
+	if stable == "" {
+		st, err := c.stableOf(table)
+		if err != nil || st == "" {
+			return false, err
+		}
+		stable = st
+	}
+	se := c.schemaEvolution(stable)
+	if se == nil {
+		return false, nil
+	}
+	c.evolveMu.Lock()
+	defer c.evolveMu.Unlock()
+	infos, err := c.DescribeStable(stable)
+	if err != nil {
+		return false, err
+	}
+	plan, err := planNewColumns(infos, rows, se)
+	if err != nil {
+		return false, err
+	}
+	for _, col := range plan {
+		if err := c.AddColumnToStable(stable, col); err != nil && !isAlreadyExists(err) && !isDuplicateColumn(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// planNewColumns 计算需要添加的列，检查白名单与列数上限
+func planNewColumns(infos []ColumnInfo, rows []map[string]interface{}, se *SchemaEvolution) ([]ColumnDef, error) {
+	existing := map[string]bool{}
+	count := 0
+	for _, info := range infos {
+		existing[strings.ToLower(info.Name)] = true
+		if !info.IsTag {
+			count++
+		}
+	}
+	samples := map[string]interface{}{}
+	var names []string
+	for _, row := range rows {
+		for k, v := range row {
+			key := strings.ToLower(k)
+			if key == "ts" || existing[key] {
+				continue
+			}
+			if _, seen := samples[key]; !seen {
+				names = append(names, k)
+				samples[key] = nil
+			}
+			if samples[key] == nil {
+				samples[key] = v
+			}
+		}
+	}
+	sort.Strings(names)
+	plan := make([]ColumnDef, 0, len(names))
+	for _, name := range names {
+		if _, err := sanitizeIdent(name); err != nil {
+			return nil, err
+		}
+		if !se.allows(name) {
+			return nil, fmt.Errorf("列 %s 不在自动添加白名单中", name)
+		}
+		typ := ""
+		for k, t := range se.Types {
+			if strings.EqualFold(k, name) {
+				typ = t
+				break
+			}
+		}
+		if typ == "" {
+			typ = inferColumnType(samples[strings.ToLower(name)])
+			if typ == "" {
+				return nil, fmt.Errorf("无法推断列 %s 的类型: %T", name, samples[strings.ToLower(name)])
+			}
+		}
+		if count+len(plan)+1 > se.MaxColumns {
+			return nil, fmt.Errorf("添加列 %s 将超过列数上限 %d", name, se.MaxColumns)
+		}
+		plan = append(plan, ColumnDef{Name: name, Type: typ})
+	}
+	return plan, nil
+}
+
+// inferColumnType 按 Go 值推断 TDengine 列类型，无法推断时返回空串
+// 字符串按长度取 VARCHAR(64) 起的 2 的幂，留出增长空间
+func inferColumnType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return "TIMESTAMP"
+	case string:
+		return fmt.Sprintf("VARCHAR(%d)", varLength(len(val)))
+	case []byte:
+		return fmt.Sprintf("VARBINARY(%d)", varLength(len(val)))
+	case VarBinary:
+		return fmt.Sprintf("VARBINARY(%d)", varLength(len(val)))
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool:
+		return "BOOL"
+	case reflect.Int8:
+		return "TINYINT"
+	case reflect.Int16:
+		return "SMALLINT"
+	case reflect.Int32:
+		return "INT"
+	case reflect.Int, reflect.Int64:
+		return "BIGINT"
+	case reflect.Uint8:
+		return "TINYINT UNSIGNED"
+	case reflect.Uint16:
+		return "SMALLINT UNSIGNED"
+	case reflect.Uint32:
+		return "INT UNSIGNED"
+	case reflect.Uint, reflect.Uint64:
+		return "BIGINT UNSIGNED"
+	case reflect.Float32:
+		return "FLOAT"
+	case reflect.Float64:
+		return "DOUBLE"
+	case reflect.String:
+		return fmt.Sprintf("VARCHAR(%d)", varLength(reflect.ValueOf(v).Len()))
+	}
+	return ""
+}
+
+func varLength(n int) int {
+	l := 64
+	for l < n && l < 16384 {
+		l *= 2
+	}
+	return l
+}
+
+// stableOf 查询子表所属的超级表，普通表返回空串
+func (c *Client) stableOf(table string) (string, error) {
+	tbl, err := sanitizeIdent(table)
+	if err != nil {
+		return "", err
+	}
+	q := fmt.Sprintf("SELECT stable_name FROM information_schema.ins_tables WHERE table_name = '%s'", tbl)
+	if db := c.currentDB(); db != "" {
+		q += fmt.Sprintf(" AND db_name = '%s'", db)
+	}
+	rows, err := c.queryMaps(q)
+	if err != nil || len(rows) == 0 {
+		return "", err
+	}
+	return rowString(rows[0], "stable_name"), nil
+}
+
+// isUnknownColumn 判断错误是否由写入不存在的列引起
+func isUnknownColumn(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "invalid column name") || strings.Contains(msg, "column not exist")
+}
+
+func isDuplicateColumn(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "duplicated column")
+}
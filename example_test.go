@@ -44,7 +44,7 @@ func ExampleFilter() {
 		OrderBy: "ts",
 		Limit:   5,
 	}
-	where, _ := f.buildWhere()
+	where, _ := f.buildWhere(nil)
 	post, _ := f.buildOrderLimit()
 	fmt.Println(where)
 	fmt.Println(post)
@@ -17,12 +17,14 @@ import (
 
 // Condition 单个筛选条件，如 column op value
 // Op 示例：=, >, >=, <, <=, <> , LIKE, BETWEEN, IN
+// JSON TAG：设置 JSONKey 时列渲染为 col->'key'；Op 为 CONTAINS 或 ? 时判断键是否存在
 // 注意：REST 连接不一定支持参数绑定，需谨慎构造 SQL
 // ORM 通过列名白名单和简单转义降低风险
 type Condition struct {
-	Column string
-	Op     string
-	Value  interface{}
+	Column  string
+	Op      string
+	Value   interface{}
+	JSONKey string // 可选，JSON TAG 的键，如 info->'k1'
 }
 
 // Filter 组合筛选
@@ -35,7 +37,7 @@ type Filter struct {
 	Limit      int
 }
 
-func (f Filter) buildWhere() (string, error) {
+func (f Filter) buildWhere(vc *valueCodec) (string, error) {
 	if len(f.Conditions) == 0 {
 		return "", nil
 	}
@@ -50,6 +52,26 @@ func (f Filter) buildWhere() (string, error) {
 			return "", err
 		}
 		op := strings.ToUpper(strings.TrimSpace(c.Op))
+		if op == "CONTAINS" || op == "?" {
+			key, ok := c.Value.(string)
+			if !ok {
+				return "", fmt.Errorf("%s 需要 string 类型的 JSON 键", op)
+			}
+			fv, err := vc.formatValue(key)
+			if err != nil {
+				return "", err
+			}
+			// ? 为 CONTAINS 的别名（判断 JSON 键是否存在）
+			parts = append(parts, fmt.Sprintf("%s CONTAINS %s", col, fv))
+			continue
+		}
+		if c.JSONKey != "" {
+			key, err := vc.formatValue(c.JSONKey)
+			if err != nil {
+				return "", err
+			}
+			col = col + "->" + key
+		}
 		if op == "IN" {
 			arr, ok := c.Value.([]interface{})
 			if !ok {
@@ -57,7 +79,7 @@ func (f Filter) buildWhere() (string, error) {
 			}
 			vals := make([]string, 0, len(arr))
 			for _, v := range arr {
-				fv, err := formatValue(v)
+				fv, err := vc.formatValue(v)
 				if err != nil {
 					return "", err
 				}
@@ -71,18 +93,22 @@ func (f Filter) buildWhere() (string, error) {
 			if !ok {
 				return "", fmt.Errorf("BETWEEN 需要 [2]interface{}")
 			}
-			l, err := formatValue(rng[0])
+			l, err := vc.formatValue(rng[0])
 			if err != nil {
 				return "", err
 			}
-			r, err := formatValue(rng[1])
+			r, err := vc.formatValue(rng[1])
 			if err != nil {
 				return "", err
 			}
 			parts = append(parts, fmt.Sprintf("%s BETWEEN %s AND %s", col, l, r))
 			continue
 		}
-		fv, err := formatValue(c.Value)
+		if pattern, ok := c.Value.(string); ok && (op == "LIKE" || op == "NOT LIKE") {
+			parts = append(parts, fmt.Sprintf("%s %s %s", col, op, quoteLike(pattern)))
+			continue
+		}
+		fv, err := vc.formatValue(c.Value)
 		if err != nil {
 			return "", err
 		}
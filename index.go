@@ -0,0 +1,186 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: TAG 索引管理（CREATE INDEX / DROP INDEX / ins_indexes）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// IndexInfo 描述一个索引（information_schema.ins_indexes）
+type IndexInfo struct {
+	Name   string
+	DB     string
+	Table  string // 超级表名
+	Column string // 被索引的 TAG
+	Type   string // 如 tag
+}
+
+// maxIndexNameLen 生成的索引名长度上限，超出时改用哈希命名
+const maxIndexNameLen = 64
+
+// CreateTagIndex 为超级表的 TAG 创建索引（幂等），返回索引名
+// 该 TAG 已有索引（包括服务端为第一个 TAG 自动创建的索引）时直接返回已有索引名；
+// 新索引命名为 idx_<stable>_<tag>
+// stable 可写作 db.stable；未带库名时使用 DSN 中的库，两者都没有时返回错误，避免匹配到其他库中同名超级表的索引
+func (c *Client) CreateTagIndex(stable, tag string) (string, error) {
+	db, st, err := c.splitDBTable(stable)
+	if err != nil {
+		return "", err
+	}
+	tg, err := sanitizeIdent(tag)
+	if err != nil {
+		return "", err
+	}
+	if db == "" {
+		return "", fmt.Errorf("无法确定超级表 %s 所在的库：请在 DSN 中指定库或使用 db.stable 形式", st)
+	}
+	indexes, err := c.listIndexes(db, st)
+	if err != nil {
+		return "", err
+	}
+	for _, idx := range indexes {
+		if strings.EqualFold(idx.Column, tg) {
+			return idx.Name, nil
+		}
+	}
+	name := tagIndexName(st, tg)
+	if _, err := c.DB.Exec(fmt.Sprintf("CREATE INDEX %s.%s ON %s.%s (%s)", db, name, db, st, tg)); err != nil && !isAlreadyExists(err) {
+		return "", err
+	}
+	return name, nil
+}
+
+// splitDBTable 拆分 db.table 形式的表名；未带库名时库名取 DSN 中的库（可能为空）
+func (c *Client) splitDBTable(name string) (string, string, error) {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		db, err := sanitizeIdent(name[:i])
+		if err != nil {
+			return "", "", err
+		}
+		tbl, err := sanitizeIdent(name[i+1:])
+		if err != nil {
+			return "", "", err
+		}
+		return db, tbl, nil
+	}
+	tbl, err := sanitizeIdent(name)
+	if err != nil {
+		return "", "", err
+	}
+	return c.currentDB(), tbl, nil
+}
+
+// tagIndexName 生成索引名：idx_<stable>_<tag>，过长时使用 idx_<哈希>
+func tagIndexName(stable, tag string) string {
+	name := strings.ToLower("idx_" + stable + "_" + tag)
+	if len(name) <= maxIndexNameLen {
+		return name
+	}
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(stable + "." + tag)))
+	return fmt.Sprintf("idx_%016x", h.Sum64())
+}
+
+// DropIndex 删除索引；name 可写作 db.name 指定库，未带库名时使用 DSN 中的库
+func (c *Client) DropIndex(name string, ifExists bool) error {
+	db, idx, err := c.splitDBTable(name)
+	if err != nil {
+		return err
+	}
+	if db != "" {
+		idx = db + "." + idx
+	}
+	sqlStr := "DROP INDEX "
+	if ifExists {
+		sqlStr += "IF EXISTS "
+	}
+	_, err = c.DB.Exec(sqlStr + idx)
+	return err
+}
+
+// ListIndexes 列出当前库中超级表的索引；stable 为空时列出全部，可写作 db.stable 指定库
+func (c *Client) ListIndexes(stable string) ([]IndexInfo, error) {
+	if stable == "" {
+		return c.listIndexes(c.currentDB(), "")
+	}
+	db, st, err := c.splitDBTable(stable)
+	if err != nil {
+		return nil, err
+	}
+	return c.listIndexes(db, st)
+}
+
+// listIndexes 查询 ins_indexes，db、stable 为空时不按其筛选
+func (c *Client) listIndexes(db, stable string) ([]IndexInfo, error) {
+	q := "SELECT * FROM information_schema.ins_indexes"
+	var conds []string
+	if stable != "" {
+		conds = append(conds, fmt.Sprintf("table_name = '%s'", stable))
+	}
+	if db != "" {
+		conds = append(conds, fmt.Sprintf("db_name = '%s'", db))
+	}
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := c.queryMaps(q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]IndexInfo, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, IndexInfo{
+			Name:   rowString(r, "index_name"),
+			DB:     rowString(r, "db_name"),
+			Table:  rowString(r, "table_name"),
+			Column: rowString(r, "column_name"),
+			Type:   rowString(r, "index_type"),
+		})
+	}
+	return out, nil
+}
+
+// currentDB 返回 DSN 中指定的库名，未指定或非法时返回空串
+func (c *Client) currentDB() string {
+	if c.restCfg == nil || c.restCfg.DbName == "" {
+		return ""
+	}
+	db, err := sanitizeIdent(c.restCfg.DbName)
+	if err != nil {
+		return ""
+	}
+	return db
+}
+
+// CreateTagIndexMsg 创建 TAG 索引并返回提示
+func (c *Client) CreateTagIndexMsg(stable, tag string) (string, string, error) {
+	name, err := c.CreateTagIndex(stable, tag)
+	if err != nil {
+		return "", "", fmt.Errorf("CreateTagIndex %s.%s failed: %w", stable, tag, err)
+	}
+	return name, fmt.Sprintf("索引已就绪: %s", name), nil
+}
+
+// DropIndexMsg 删除索引并返回提示
+func (c *Client) DropIndexMsg(name string) (string, error) {
+	if err := c.DropIndex(name, true); err != nil {
+		return "", fmt.Errorf("DropIndex %s failed: %w", name, err)
+	}
+	return fmt.Sprintf("索引已删除: %s", name), nil
+}
+
+// ListIndexesMsg 列出索引并返回提示
+func (c *Client) ListIndexesMsg(stable string) ([]IndexInfo, string, error) {
+	indexes, err := c.ListIndexes(stable)
+	if err != nil {
+		return nil, "", fmt.Errorf("ListIndexes failed: %w", err)
+	}
+	return indexes, fmt.Sprintf("共 %d 个索引", len(indexes)), nil
+}
@@ -0,0 +1,113 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: INSERT ... SELECT（服务端复制、回填聚合结果）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SelectQuery 描述一条 SELECT 语句，复用 Filter、INTERVAL/FILL 与 TAGS 分组的构造
+// 设置 Interval 时 GroupTags 生成 PARTITION BY（按 TAG 分窗口），否则生成 GROUP BY
+// Exprs 为选择表达式，如 "_wstart", "avg(current)"；为空时为 *
+// 注意：Exprs 原样拼接，与 QueryAggregateAcrossStable 的 aggExpr 一样，不应来自外部输入
+type SelectQuery struct {
+	Exprs     []string
+	From      string
+	Filter    Filter
+	GroupTags []string
+	Interval  time.Duration
+	Fill      string
+}
+
+// build 生成 SELECT 语句
+func (q SelectQuery) build(vc *valueCodec) (string, error) {
+	from, err := sanitizeIdent(q.From)
+	if err != nil {
+		return "", err
+	}
+	exprs := "*"
+	if len(q.Exprs) > 0 {
+		exprs = strings.Join(q.Exprs, ", ")
+	}
+	where, err := q.Filter.buildWhere(vc)
+	if err != nil {
+		return "", err
+	}
+	// 按窗口聚合时须用 PARTITION BY 分组，GROUP BY 不能与 INTERVAL 同时使用
+	buildTags := buildGroupByTags
+	if q.Interval > 0 {
+		buildTags = buildPartitionByTags
+	}
+	group, err := buildTags(q.GroupTags)
+	if err != nil {
+		return "", err
+	}
+	post, err := q.Filter.buildOrderLimit()
+	if err != nil {
+		return "", err
+	}
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s", exprs, from)
+	if where != "" {
+		sqlStr += " " + where
+	}
+	return sqlStr + group + buildIntervalFill(q.Interval, q.Fill) + post, nil
+}
+
+// InsertFromQuery 将查询结果直接写入目标表：INSERT INTO target (columns) SELECT ...
+// 整个过程在服务端执行，适合回填降采样结果或在表之间搬运数据；columns 为空时按目标表列顺序写入
+// 查询结果的第一列需为时间戳（如 _wstart 或 ts）
+func (c *Client) InsertFromQuery(target string, columns []string, q SelectQuery) (int64, error) {
+	sqlStr, err := buildInsertSelectSQL(target, columns, q, c.codec())
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.DB.Exec(sqlStr)
+	if err != nil {
+		return 0, c.invalidateOnMissing(target, err)
+	}
+	return res.RowsAffected()
+}
+
+// buildInsertSelectSQL 生成 INSERT INTO ... SELECT 语句
+func buildInsertSelectSQL(target string, columns []string, q SelectQuery, vc *valueCodec) (string, error) {
+	tbl, err := sanitizeIdent(target)
+	if err != nil {
+		return "", err
+	}
+	sel, err := q.build(vc)
+	if err != nil {
+		return "", err
+	}
+	if len(columns) > 0 && len(q.Exprs) > 0 && len(columns) != len(q.Exprs) {
+		return "", fmt.Errorf("目标列数 %d 与查询表达式数 %d 不一致", len(columns), len(q.Exprs))
+	}
+	cols := ""
+	if len(columns) > 0 {
+		parts := make([]string, 0, len(columns))
+		for _, col := range columns {
+			v, err := sanitizeIdent(col)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, v)
+		}
+		cols = " (" + strings.Join(parts, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s%s %s", tbl, cols, sel), nil
+}
+
+// InsertFromQueryMsg 执行 INSERT ... SELECT 并返回提示
+func (c *Client) InsertFromQueryMsg(target string, columns []string, q SelectQuery) (int64, string, error) {
+	n, err := c.InsertFromQuery(target, columns, q)
+	if err != nil {
+		return 0, "", fmt.Errorf("InsertFromQuery failed: %w", err)
+	}
+	return n, fmt.Sprintf("已从查询写入 %d 行到 %s", n, target), nil
+}
@@ -0,0 +1,92 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: JSON TAG 支持（编码、校验与结果解码）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// isJSONType 判断类型定义是否为 JSON
+func isJSONType(typ string) bool {
+	return strings.EqualFold(strings.TrimSpace(typ), "JSON")
+}
+
+// validateJSONTags 校验 JSON 类型的使用：只能作为 TAG，且超级表只能有这一个 TAG
+func validateJSONTags(columns []ColumnDef, tagColumns []ColumnDef) error {
+	for _, col := range columns {
+		if isJSONType(col.Type) {
+			return fmt.Errorf("JSON 类型只能用于 TAG: %s", col.Name)
+		}
+	}
+	for _, tag := range tagColumns {
+		if isJSONType(tag.Type) && len(tagColumns) > 1 {
+			return fmt.Errorf("使用 JSON TAG 时超级表只能有一个 TAG: %s", tag.Name)
+		}
+	}
+	return nil
+}
+
+// formatTagValue 格式化 TAG 值；map、struct 与 json.RawMessage 按 JSON 编码，其余同 formatValue
+func (vc *valueCodec) formatTagValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case json.RawMessage:
+		if !json.Valid(val) {
+			return "", fmt.Errorf("非法 JSON TAG 值: %s", string(val))
+		}
+		return quoteJSON(string(val)), nil
+	case time.Time, driver.Valuer:
+		return vc.formatValue(val)
+	}
+	if vc.lookupEncoder(v) != nil {
+		return vc.formatValue(v)
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("JSON TAG 编码失败: %w", err)
+		}
+		return quoteJSON(string(b)), nil
+	}
+	return vc.formatValue(v)
+}
+
+// quoteJSON 将 JSON 文本转为 SQL 字符串字面量，转义反斜杠与单引号
+func quoteJSON(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// decodeJSONValue 将查询结果中的 JSON 值解码为 map[string]interface{}，无法解码时原样返回
+func decodeJSONValue(v interface{}) interface{} {
+	var raw []byte
+	switch val := v.(type) {
+	case []byte:
+		raw = val
+	case json.RawMessage:
+		raw = val
+	case string:
+		raw = []byte(val)
+	default:
+		return v
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return v
+	}
+	return out
+}
@@ -0,0 +1,273 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 子表命名策略（按 TAGS 推导子表名）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubTableNamer 根据超级表名与 TAGS 值推导子表名
+// 同一组 TAGS 必须始终得到同一个名字，以便多个服务写入同一子表
+type SubTableNamer func(stable string, tags map[string]interface{}) (string, error)
+
+// TemplateNamer 按模板生成子表名，占位符为 {tag名}，例如 "meter_{device_id}"
+// TAG 值中的非法字符（非字母、数字、下划线）会被替换为下划线，并追加原值的短哈希以免不同的值得到同一个名字
+func TemplateNamer(tpl string) SubTableNamer {
+	return func(stable string, tags map[string]interface{}) (string, error) {
+		var sb strings.Builder
+		rest := tpl
+		for {
+			start := strings.IndexByte(rest, '{')
+			if start < 0 {
+				sb.WriteString(rest)
+				break
+			}
+			end := strings.IndexByte(rest[start:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("命名模板缺少 '}': %s", tpl)
+			}
+			sb.WriteString(rest[:start])
+			key := rest[start+1 : start+end]
+			v, ok := lookupTag(tags, key)
+			if !ok {
+				return "", fmt.Errorf("命名模板引用的 TAG 不存在: %s", key)
+			}
+			sb.WriteString(identPart(tagString(v)))
+			rest = rest[start+end+1:]
+		}
+		return sanitizeIdent(sb.String())
+	}
+}
+
+// HashNamer 按 TAGS 的稳定哈希生成子表名，形式与 schemaless 写入类似：prefix + md5
+// prefix 为空时使用 "t_"
+func HashNamer(prefix string) SubTableNamer {
+	if prefix == "" {
+		prefix = "t_"
+	}
+	return func(stable string, tags map[string]interface{}) (string, error) {
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, strings.ToLower(k))
+		}
+		sort.Strings(keys)
+		parts := []string{strings.ToLower(stable)}
+		for _, k := range keys {
+			v, _ := lookupTag(tags, k)
+			parts = append(parts, k+"="+tagString(v))
+		}
+		sum := md5.Sum([]byte(strings.Join(parts, ",")))
+		return sanitizeIdent(prefix + hex.EncodeToString(sum[:]))
+	}
+}
+
+// subTableNaming 记录某个超级表的命名策略与 TAG 顺序
+type subTableNaming struct {
+	namer   SubTableNamer
+	tagCols []string
+}
+
+// SetSubTableNamer 为超级表注册命名策略
+// tagOrder 为 TAGS 定义顺序；为空时在首次使用时通过 DESCRIBE 获取
+func (c *Client) SetSubTableNamer(stable string, namer SubTableNamer, tagOrder []string) error {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return err
+	}
+	if namer == nil {
+		return fmt.Errorf("namer 不能为空")
+	}
+	for _, t := range tagOrder {
+		if _, err := sanitizeIdent(t); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.namers == nil {
+		c.namers = make(map[string]*subTableNaming)
+	}
+	c.namers[strings.ToLower(st)] = &subTableNaming{namer: namer, tagCols: append([]string(nil), tagOrder...)}
+	return nil
+}
+
+// SubTableName 按已注册的命名策略推导子表名（不访问数据库）
+func (c *Client) SubTableName(stable string, tags map[string]interface{}) (string, error) {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return "", err
+	}
+	c.mu.RLock()
+	n := c.namers[strings.ToLower(st)]
+	c.mu.RUnlock()
+	if n == nil {
+		return "", fmt.Errorf("超级表 %s 未设置命名策略", st)
+	}
+	if err := checkTagKeys(tags); err != nil {
+		return "", err
+	}
+	return n.namer(st, tags)
+}
+
+// EnsureSubTableByTags 按命名策略推导子表名并确保子表存在，返回子表名
+func (c *Client) EnsureSubTableByTags(stable string, tags map[string]interface{}) (string, error) {
+	sub, err := c.SubTableName(stable, tags)
+	if err != nil {
+		return "", err
+	}
+	tagValues, err := c.orderedTagValues(stable, tags)
+	if err != nil {
+		return "", err
+	}
+	if err := c.EnsureSubTable(sub, stable, tagValues); err != nil {
+		return "", err
+	}
+	return sub, nil
+}
+
+// InsertByTags 按 TAGS 推导子表名并插入一行（子表不存在时在同一语句内创建），返回子表名
+func (c *Client) InsertByTags(stable string, tags map[string]interface{}, row map[string]interface{}) (string, error) {
+	sub, err := c.SubTableName(stable, tags)
+	if err != nil {
+		return "", err
+	}
+	tagValues, err := c.orderedTagValues(stable, tags)
+	if err != nil {
+		return "", err
+	}
+	return sub, c.InsertAuto(sub, stable, tagValues, row)
+}
+
+// orderedTagValues 将 TAGS map 按超级表定义顺序转为位置参数，缺失的 TAG 为 NULL
+func (c *Client) orderedTagValues(stable string, tags map[string]interface{}) ([]interface{}, error) {
+	if err := checkTagKeys(tags); err != nil {
+		return nil, err
+	}
+	key := strings.ToLower(stable)
+	c.mu.RLock()
+	n := c.namers[key]
+	var order []string
+	if n != nil {
+		order = n.tagCols
+	}
+	c.mu.RUnlock()
+	if len(order) == 0 {
+		infos, err := c.DescribeStable(stable)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.IsTag {
+				order = append(order, info.Name)
+			}
+		}
+		if n != nil {
+			c.mu.Lock()
+			n.tagCols = order
+			c.mu.Unlock()
+		}
+	}
+	for k := range tags {
+		found := false
+		for _, t := range order {
+			if strings.EqualFold(t, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("超级表 %s 不存在 TAG: %s", stable, k)
+		}
+	}
+	vals := make([]interface{}, len(order))
+	for i, t := range order {
+		vals[i], _ = lookupTag(tags, t)
+	}
+	return vals, nil
+}
+
+// checkTagKeys 检查 TAGS map 中没有仅大小写不同的键：TDengine 标识符不区分大小写，
+// 这样的键指向同一个 TAG，取哪个值不确定
+func checkTagKeys(tags map[string]interface{}) error {
+	seen := make(map[string]string, len(tags))
+	for k := range tags {
+		lk := strings.ToLower(k)
+		if prev, ok := seen[lk]; ok {
+			return fmt.Errorf("TAG %s 与 %s 仅大小写不同，指向同一个 TAG", prev, k)
+		}
+		seen[lk] = k
+	}
+	return nil
+}
+
+// tagString 将 TAG 值规范化为与进程、时区无关的文本，保证各服务推导出相同的子表名
+// time.Time 转为 UTC 的 RFC3339Nano（去掉单调时钟读数与时区名），数值使用 strconv，指针取其指向的值
+func tagString(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Sprintf("%v", nil)
+		}
+		rv = rv.Elem()
+		v = rv.Interface()
+	}
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits())
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// lookupTag 不区分大小写地查找 TAG 值（TDengine 标识符不区分大小写）
+func lookupTag(tags map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := tags[name]; ok {
+		return v, true
+	}
+	for k, v := range tags {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// identPart 将任意字符串转换为可用于标识符的片段
+// 含非法字符时逐字节替换为下划线，并追加原值 md5 的前 8 位，避免 a-b、a.b、a b 得到同一个名字
+func identPart(s string) string {
+	b := []byte(s)
+	changed := false
+	for i, ch := range b {
+		if !(ch == '_' || (ch >= '0' && ch <= '9') || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z')) {
+			b[i] = '_'
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	sum := md5.Sum([]byte(s))
+	return string(b) + "_" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,136 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 表选项（TTL、COMMENT、MAX_DELAY、ROLLUP）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TableOptions 建表/改表选项
+// 子表与普通表支持 Comment、TTL；超级表支持 Comment、MaxDelay、Rollup
+type TableOptions struct {
+	Comment  string        // 表注释
+	TTL      *int          // 生存时间（天），nil 表示不设置，0 表示不过期，仅子表/普通表；可用 TTLDays 构造
+	MaxDelay time.Duration // ROLLUP 结果的最大延迟，仅超级表，需配合 Rollup
+	Rollup   string        // ROLLUP 聚合函数：avg, sum, min, max, last, first，仅超级表
+}
+
+// TTLDays 返回 TableOptions.TTL 所需的指针
+func TTLDays(days int) *int {
+	return &days
+}
+
+var rollupFuncs = map[string]bool{"avg": true, "sum": true, "min": true, "max": true, "last": true, "first": true}
+
+// buildTableOptions 生成表选项子句（带前导空格），forStable 表示超级表
+func buildTableOptions(opts []TableOptions, forStable bool) (string, error) {
+	if len(opts) == 0 {
+		return "", nil
+	}
+	if len(opts) > 1 {
+		return "", fmt.Errorf("最多只能传入一个 TableOptions，实际 %d 个", len(opts))
+	}
+	o := opts[0]
+	parts := []string{}
+	if o.Comment != "" {
+		cm, err := formatValue(o.Comment)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "COMMENT "+cm)
+	}
+	if o.TTL != nil && *o.TTL < 0 {
+		return "", fmt.Errorf("TTL 不能为负数: %d", *o.TTL)
+	}
+	if forStable {
+		if o.TTL != nil {
+			return "", fmt.Errorf("超级表不支持 TTL 选项")
+		}
+		if o.MaxDelay > 0 && o.Rollup == "" {
+			return "", fmt.Errorf("MAX_DELAY 需要同时指定 ROLLUP")
+		}
+		if o.MaxDelay > 0 {
+			parts = append(parts, "MAX_DELAY "+formatDurationLiteral(o.MaxDelay))
+		}
+		if o.Rollup != "" {
+			fn := strings.ToLower(strings.TrimSpace(o.Rollup))
+			if !rollupFuncs[fn] {
+				return "", fmt.Errorf("不支持的 ROLLUP 函数: %s", o.Rollup)
+			}
+			parts = append(parts, fmt.Sprintf("ROLLUP(%s)", fn))
+		}
+	} else {
+		if o.MaxDelay > 0 || o.Rollup != "" {
+			return "", fmt.Errorf("MAX_DELAY/ROLLUP 仅适用于超级表")
+		}
+		if o.TTL != nil {
+			parts = append(parts, fmt.Sprintf("TTL %d", *o.TTL))
+		}
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return " " + strings.Join(parts, " "), nil
+}
+
+// formatDurationLiteral 将时长格式化为 TDengine 时间字面量：整秒用 s，否则用 ms
+func formatDurationLiteral(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms%1000 == 0 {
+		return fmt.Sprintf("%ds", ms/1000)
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// AlterTableOptions 修改子表或普通表的 COMMENT 与 TTL
+// 注意：Comment 为空时不修改注释；TTL 为 nil 时不修改 TTL，TTLDays(0) 取消过期
+func (c *Client) AlterTableOptions(table string, opts TableOptions) error {
+	tbl, err := sanitizeIdent(table)
+	if err != nil {
+		return err
+	}
+	clause, err := buildTableOptions([]TableOptions{opts}, false)
+	if err != nil {
+		return err
+	}
+	if clause == "" {
+		return fmt.Errorf("没有需要修改的选项")
+	}
+	_, err = c.DB.Exec("ALTER TABLE " + tbl + clause)
+	return err
+}
+
+// AlterStableOptions 修改超级表注释（MAX_DELAY、ROLLUP 建表后不可修改）
+func (c *Client) AlterStableOptions(stable string, opts TableOptions) error {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return err
+	}
+	if opts.MaxDelay > 0 || opts.Rollup != "" {
+		return fmt.Errorf("MAX_DELAY/ROLLUP 建表后不可修改")
+	}
+	clause, err := buildTableOptions([]TableOptions{opts}, true)
+	if err != nil {
+		return err
+	}
+	if clause == "" {
+		return fmt.Errorf("没有需要修改的选项")
+	}
+	_, err = c.DB.Exec("ALTER STABLE " + st + clause)
+	return err
+}
+
+// AlterTableOptionsMsg 修改表选项并返回提示
+func (c *Client) AlterTableOptionsMsg(table string, opts TableOptions) (string, error) {
+	if err := c.AlterTableOptions(table, opts); err != nil {
+		return "", fmt.Errorf("AlterTableOptions %s failed: %w", table, err)
+	}
+	return fmt.Sprintf("表选项已修改: %s", table), nil
+}
@@ -0,0 +1,587 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 参数绑定（stmt）高吞吐写入，基于 WebSocket 连接
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/ws/stmt"
+)
+
+// PreparedInserterOptions 参数绑定写入配置
+type PreparedInserterOptions struct {
+	URL       string // WebSocket 地址，如 ws://127.0.0.1:6041；为空时由 DSN 推导
+	Precision string // 库的时间精度：ms（默认）、us、ns
+}
+
+// PreparedInserter 通过 stmt 接口按列绑定参数写入某个超级表的子表
+// 使用与 BatchInsert 相同的行模型（map），子表不存在时按 TAGS 自动创建
+// 同一实例的调用会被串行化；使用完毕需调用 Close
+type PreparedInserter struct {
+	mu        sync.Mutex
+	c         *Client
+	conn      *stmt.Connector
+	st        preparedStmt                 // nil 表示需要重新 prepare
+	prepare   func() (preparedStmt, error) // 新建并 prepare 一个 stmt
+	stable    string
+	cols      []ColumnInfo // 绑定的列，首列为时间戳
+	tags      []ColumnInfo
+	precision int
+}
+
+// preparedStmt 写入用到的 stmt 方法（*stmt.Stmt 实现）
+type preparedStmt interface {
+	SetTableName(name string) error
+	SetTags(tags *param.Param, bindType *param.ColumnType) error
+	BindParam(params []*param.Param, bindType *param.ColumnType) error
+	AddBatch() error
+	Exec() error
+	GetAffectedRows() int
+	Close() error
+}
+
+// NewPreparedInserter 为超级表创建参数绑定写入器
+// columns 为写入的列（不含 ts），为空时写入全部普通列
+func (c *Client) NewPreparedInserter(stable string, columns []string, opts PreparedInserterOptions) (*PreparedInserter, error) {
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return nil, err
+	}
+	precision, err := stmtPrecision(opts.Precision)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := c.DescribeStable(st)
+	if err != nil {
+		return nil, err
+	}
+	cols, tags, err := selectBindColumns(infos, columns)
+	if err != nil {
+		return nil, err
+	}
+	wsURL := opts.URL
+	if wsURL == "" {
+		if wsURL, err = c.wsURL(); err != nil {
+			return nil, err
+		}
+	}
+	cfg := stmt.NewConfig(wsURL, 0)
+	if c.restCfg != nil {
+		cfg.SetConnectUser(c.restCfg.User)
+		cfg.SetConnectPass(c.restCfg.Passwd)
+		cfg.SetConnectDB(c.restCfg.DbName)
+	}
+	conn, err := stmt.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr := buildPreparedInsertSQL(st, cols, tags)
+	prepare := func() (preparedStmt, error) {
+		s, err := conn.Init()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Prepare(sqlStr); err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+	s, err := prepare()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &PreparedInserter{c: c, conn: conn, st: s, prepare: prepare, stable: st, cols: cols, tags: tags, precision: precision}, nil
+}
+
+// Insert 向单个子表写入多行
+func (p *PreparedInserter) Insert(table string, tagValues []interface{}, rows []map[string]interface{}) (int, error) {
+	return p.InsertTables([]TableRows{{Table: table, Tags: tagValues, Rows: rows}})
+}
+
+// InsertTables 向多个子表写入并一次提交，返回写入行数；TableRows.Stable 被忽略
+// 所有子表先完成校验与参数构造再加入批次；加入批次或执行失败时丢弃该 stmt 并重新 prepare，
+// 已加入的批次不会残留到下一次调用
+func (p *PreparedInserter) InsertTables(tables []TableRows) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	type boundTable struct {
+		name     string
+		tags     *param.Param
+		tagTypes *param.ColumnType
+		params   []*param.Param
+		colTypes *param.ColumnType
+	}
+	var bound []boundTable
+	for _, t := range tables {
+		if len(t.Rows) == 0 {
+			continue
+		}
+		tbl, err := sanitizeIdent(t.Table)
+		if err != nil {
+			return 0, err
+		}
+		if len(t.Tags) != len(p.tags) {
+			return 0, fmt.Errorf("子表 %s 的 TAG 数量应为 %d，实际 %d", tbl, len(p.tags), len(t.Tags))
+		}
+		tagParam, tagTypes, err := bindTags(p.tags, t.Tags, p.precision)
+		if err != nil {
+			return 0, fmt.Errorf("子表 %s: %w", tbl, err)
+		}
+		params, colTypes, err := bindColumns(p.cols, t.Rows, p.precision, p.tsFiller(tbl))
+		if err != nil {
+			return 0, fmt.Errorf("子表 %s: %w", tbl, err)
+		}
+		bound = append(bound, boundTable{tbl, tagParam, tagTypes, params, colTypes})
+	}
+	if len(bound) == 0 {
+		return 0, nil
+	}
+	if p.st == nil {
+		if err := p.reset(); err != nil {
+			return 0, err
+		}
+	}
+	for _, b := range bound {
+		err := p.st.SetTableName(b.name)
+		if err == nil {
+			err = p.st.SetTags(b.tags, b.tagTypes)
+		}
+		if err == nil {
+			err = p.st.BindParam(b.params, b.colTypes)
+		}
+		if err == nil {
+			err = p.st.AddBatch()
+		}
+		if err != nil {
+			p.reset()
+			return 0, fmt.Errorf("子表 %s: %w", b.name, err)
+		}
+	}
+	if err := p.st.Exec(); err != nil {
+		p.reset()
+		return 0, err
+	}
+	return p.st.GetAffectedRows(), nil
+}
+
+// tsFiller 为缺少 ts 的行分配时间戳，遵循 SetTimestampPolicy：stmt 只能绑定具体时间，
+// 服务端 NOW() 类策略改用客户端时钟（同一表按输入顺序至少递增 1ms，与 SQL 写入共享时钟）；TimestampRequired 时返回错误
+func (p *PreparedInserter) tsFiller(table string) func(row int) (time.Time, error) {
+	p.c.mu.RLock()
+	policy := p.c.tsPolicy
+	p.c.mu.RUnlock()
+	return func(row int) (time.Time, error) {
+		if policy == TimestampRequired {
+			return time.Time{}, fmt.Errorf("表 %s 第 %d 行缺少 ts", table, row)
+		}
+		return p.c.tsClock.next(table, time.Now()), nil
+	}
+}
+
+// reset 关闭当前 stmt（丢弃已加入的批次）并重新 prepare；失败时 st 为 nil，下次调用再重试
+func (p *PreparedInserter) reset() error {
+	if p.st != nil {
+		p.st.Close()
+		p.st = nil
+	}
+	s, err := p.prepare()
+	if err != nil {
+		return err
+	}
+	p.st = s
+	return nil
+}
+
+// Close 关闭 stmt 与 WebSocket 连接
+func (p *PreparedInserter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	if p.st != nil {
+		err = p.st.Close()
+		p.st = nil
+	}
+	if cerr := p.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// wsURL 由 REST DSN 推导 WebSocket 地址
+func (c *Client) wsURL() (string, error) {
+	if c.restCfg == nil {
+		return "", fmt.Errorf("未指定 WebSocket 地址，且客户端不是通过 NewClient 创建")
+	}
+	scheme := "ws"
+	if strings.EqualFold(c.restCfg.Net, "https") {
+		scheme = "wss"
+	}
+	port := c.restCfg.Port
+	if port == 0 {
+		port = 6041
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, c.restCfg.Addr, port), nil
+}
+
+func stmtPrecision(precision string) (int, error) {
+	switch strings.ToLower(precision) {
+	case "", "ms":
+		return common.PrecisionMilliSecond, nil
+	case "us":
+		return common.PrecisionMicroSecond, nil
+	case "ns":
+		return common.PrecisionNanoSecond, nil
+	}
+	return 0, fmt.Errorf("不支持的时间精度: %s", precision)
+}
+
+// selectBindColumns 按 columns 选出绑定列（首列为时间戳）与全部 TAG 列
+func selectBindColumns(infos []ColumnInfo, columns []string) ([]ColumnInfo, []ColumnInfo, error) {
+	var cols, tags, plain []ColumnInfo
+	for _, info := range infos {
+		if info.IsTag {
+			tags = append(tags, info)
+		} else {
+			plain = append(plain, info)
+		}
+	}
+	if len(plain) == 0 {
+		return nil, nil, fmt.Errorf("超级表没有普通列")
+	}
+	cols = append(cols, plain[0])
+	if len(columns) == 0 {
+		return append(cols, plain[1:]...), tags, nil
+	}
+	for _, name := range columns {
+		found := false
+		for _, info := range plain[1:] {
+			if strings.EqualFold(info.Name, name) {
+				cols = append(cols, info)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("超级表不存在列: %s", name)
+		}
+	}
+	return cols, tags, nil
+}
+
+// buildPreparedInsertSQL 生成 INSERT INTO ? USING st TAGS (?, ...) (列...) VALUES (?, ...)
+func buildPreparedInsertSQL(st string, cols []ColumnInfo, tags []ColumnInfo) string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.Name
+	}
+	marks := func(n int) string {
+		return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+	}
+	sqlStr := "INSERT INTO ?"
+	if len(tags) > 0 {
+		sqlStr += fmt.Sprintf(" USING %s TAGS (%s)", st, marks(len(tags)))
+	}
+	return sqlStr + fmt.Sprintf(" (%s) VALUES (%s)", strings.Join(names, ", "), marks(len(cols)))
+}
+
+// bindTags 构造 TAG 参数
+func bindTags(tags []ColumnInfo, values []interface{}, precision int) (*param.Param, *param.ColumnType, error) {
+	p := param.NewParam(len(tags))
+	types := param.NewColumnType(len(tags))
+	for i, tag := range tags {
+		if err := addBindType(types, tag); err != nil {
+			return nil, nil, fmt.Errorf("TAG %s: %w", tag.Name, err)
+		}
+		if err := setBindValue(p, i, tag, values[i], precision); err != nil {
+			return nil, nil, fmt.Errorf("TAG %s: %w", tag.Name, err)
+		}
+	}
+	return p, types, nil
+}
+
+// bindColumns 按列构造参数（列式绑定），缺失的列为 NULL，缺失的时间戳由 fillTS 按行分配
+func bindColumns(cols []ColumnInfo, rows []map[string]interface{}, precision int, fillTS func(row int) (time.Time, error)) ([]*param.Param, *param.ColumnType, error) {
+	params := make([]*param.Param, len(cols))
+	types := param.NewColumnType(len(cols))
+	for i, col := range cols {
+		if err := addBindType(types, col); err != nil {
+			return nil, nil, fmt.Errorf("列 %s: %w", col.Name, err)
+		}
+		params[i] = param.NewParam(len(rows))
+		for j, r := range rows {
+			v, ok := lookupTag(r, col.Name)
+			if i == 0 && (!ok || v == nil) {
+				ts, err := fillTS(j)
+				if err != nil {
+					return nil, nil, err
+				}
+				v = ts
+			}
+			if err := setBindValue(params[i], j, col, v, precision); err != nil {
+				return nil, nil, fmt.Errorf("第 %d 行列 %s: %w", j, col.Name, err)
+			}
+		}
+	}
+	return params, types, nil
+}
+
+// addBindType 按列定义追加绑定类型，DECIMAL 等不支持绑定的类型返回错误
+func addBindType(types *param.ColumnType, col ColumnInfo) error {
+	switch typ := strings.ToUpper(col.Type); {
+	case typ == "TIMESTAMP":
+		types.AddTimestamp()
+	case typ == "BOOL":
+		types.AddBool()
+	case typ == "TINYINT":
+		types.AddTinyint()
+	case typ == "SMALLINT":
+		types.AddSmallint()
+	case typ == "INT":
+		types.AddInt()
+	case typ == "BIGINT":
+		types.AddBigint()
+	case typ == "TINYINT UNSIGNED":
+		types.AddUTinyint()
+	case typ == "SMALLINT UNSIGNED":
+		types.AddUSmallint()
+	case typ == "INT UNSIGNED":
+		types.AddUInt()
+	case typ == "BIGINT UNSIGNED":
+		types.AddUBigint()
+	case typ == "FLOAT":
+		types.AddFloat()
+	case typ == "DOUBLE":
+		types.AddDouble()
+	case typ == "NCHAR":
+		types.AddNchar(col.Length)
+	case typ == "VARBINARY":
+		types.AddVarBinary(col.Length)
+	case typ == "JSON":
+		types.AddJson(col.Length)
+	case typ == "GEOMETRY":
+		types.AddGeometry(col.Length)
+	case typ == "BINARY" || typ == "VARCHAR":
+		types.AddBinary(col.Length)
+	default:
+		return fmt.Errorf("参数绑定不支持 %s 类型", col.Type)
+	}
+	return nil
+}
+
+// setBindValue 将 Go 值按列类型写入参数的第 i 个位置
+func setBindValue(p *param.Param, i int, col ColumnInfo, v interface{}, precision int) error {
+	if v == nil {
+		p.SetNull(i)
+		return nil
+	}
+	typ := strings.ToUpper(col.Type)
+	switch {
+	case typ == "TIMESTAMP":
+		t, err := bindTime(v, precision)
+		if err != nil {
+			return err
+		}
+		p.SetTimestamp(i, t, precision)
+	case typ == "BOOL":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("需要 bool，实际 %T", v)
+		}
+		p.SetBool(i, b)
+	case strings.HasSuffix(typ, "UNSIGNED"):
+		u, err := bindUint(v, typ)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "TINYINT UNSIGNED":
+			p.SetUTinyint(i, uint(u))
+		case "SMALLINT UNSIGNED":
+			p.SetUSmallint(i, uint(u))
+		case "INT UNSIGNED":
+			p.SetUInt(i, uint(u))
+		default:
+			p.SetUBigint(i, uint(u))
+		}
+	case typ == "TINYINT" || typ == "SMALLINT" || typ == "INT" || typ == "BIGINT":
+		n, err := bindInt(v, typ)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "TINYINT":
+			p.SetTinyint(i, int(n))
+		case "SMALLINT":
+			p.SetSmallint(i, int(n))
+		case "INT":
+			p.SetInt(i, int(n))
+		default:
+			p.SetBigint(i, int(n))
+		}
+	case typ == "FLOAT" || typ == "DOUBLE":
+		f, ok := bindFloat(v)
+		if !ok {
+			return fmt.Errorf("需要数值，实际 %T", v)
+		}
+		if typ == "FLOAT" && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+			return fmt.Errorf("%v 超出 FLOAT 范围", f)
+		}
+		if typ == "FLOAT" {
+			p.SetFloat(i, float32(f))
+		} else {
+			p.SetDouble(i, f)
+		}
+	case typ == "JSON":
+		b, err := bindJSON(v)
+		if err != nil {
+			return err
+		}
+		p.SetJson(i, b)
+	case typ == "NCHAR" || typ == "VARBINARY" || typ == "GEOMETRY" || typ == "BINARY" || typ == "VARCHAR":
+		var b []byte
+		switch val := v.(type) {
+		case string:
+			b = []byte(val)
+		case []byte:
+			b = val
+		case VarBinary:
+			b = val
+		default:
+			return fmt.Errorf("需要 string 或 []byte，实际 %T", v)
+		}
+		switch typ {
+		case "NCHAR":
+			p.SetNchar(i, string(b))
+		case "VARBINARY":
+			p.SetVarBinary(i, b)
+		case "GEOMETRY":
+			p.SetGeometry(i, b)
+		default:
+			p.SetBinary(i, b)
+		}
+	default:
+		return fmt.Errorf("参数绑定不支持 %s 类型", col.Type)
+	}
+	return nil
+}
+
+// bindTime 取时间戳，与 BatchInsert 一致地接受 time.Time、按库精度的整数与时间字符串
+// 字符串支持 RFC3339 与 "2006-01-02 15:04:05[.000]"（本地时区）
+func bindTime(v interface{}, precision int) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return t, nil
+		}
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05.999999999", val, time.Local); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("无法解析时间: %s", val)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return common.TimestampConvertToTime(rv.Int(), precision), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("时间戳 %d 超出范围", rv.Uint())
+		}
+		return common.TimestampConvertToTime(int64(rv.Uint()), precision), nil
+	}
+	return time.Time{}, fmt.Errorf("需要 time.Time、整数或时间字符串，实际 %T", v)
+}
+
+// bindJSON 取 JSON TAG 的值：字符串与字节串须为合法 JSON，map 与 struct 按 formatTagValue 的规则编码
+func bindJSON(v interface{}) ([]byte, error) {
+	var b []byte
+	switch val := v.(type) {
+	case string:
+		b = []byte(val)
+	case []byte:
+		b = val
+	case json.RawMessage:
+		b = val
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("JSON TAG 编码失败: %w", err)
+		}
+		return b, nil
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("非法 JSON TAG 值: %s", string(b))
+	}
+	return b, nil
+}
+
+// bindInt 取有符号整数并检查是否超出列类型的范围
+func bindInt(v interface{}, typ string) (int64, error) {
+	rv := reflect.ValueOf(v)
+	var n int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%d 超出 %s 范围", rv.Uint(), typ)
+		}
+		n = int64(rv.Uint())
+	default:
+		return 0, fmt.Errorf("需要整数，实际 %T", v)
+	}
+	if bits := intTypeBits(typ); bits < 64 && (n < -(1<<(bits-1)) || n > 1<<(bits-1)-1) {
+		return 0, fmt.Errorf("%d 超出 %s 范围", n, typ)
+	}
+	return n, nil
+}
+
+// bindUint 取无符号整数并检查是否超出列类型的范围
+func bindUint(v interface{}, typ string) (uint64, error) {
+	rv := reflect.ValueOf(v)
+	var u uint64
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u = rv.Uint()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, fmt.Errorf("%d 超出 %s 范围", rv.Int(), typ)
+		}
+		u = uint64(rv.Int())
+	default:
+		return 0, fmt.Errorf("需要无符号整数，实际 %T", v)
+	}
+	if bits := intTypeBits(typ); bits < 64 && u > 1<<bits-1 {
+		return 0, fmt.Errorf("%d 超出 %s 范围", u, typ)
+	}
+	return u, nil
+}
+
+func bindFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	}
+	return 0, false
+}
@@ -38,6 +38,15 @@ func buildIntervalFill(interval time.Duration, fill string) string {
 
 // buildGroupByTags 生成 TAGS 分组
 func buildGroupByTags(tags []string) (string, error) {
+	return buildTagsClause("GROUP BY", tags)
+}
+
+// buildPartitionByTags 生成 TAGS 分区，与 INTERVAL 一起使用时 TDengine 3 要求 PARTITION BY 而非 GROUP BY
+func buildPartitionByTags(tags []string) (string, error) {
+	return buildTagsClause("PARTITION BY", tags)
+}
+
+func buildTagsClause(keyword string, tags []string) (string, error) {
 	if len(tags) == 0 {
 		return "", nil
 	}
@@ -49,5 +58,5 @@ func buildGroupByTags(tags []string) (string, error) {
 		}
 		parts = append(parts, id)
 	}
-	return " GROUP BY " + strings.Join(parts, ", "), nil
+	return " " + keyword + " " + strings.Join(parts, ", "), nil
 }
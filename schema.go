@@ -0,0 +1,340 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 库结构导出与导入（DDL 脚本）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportSchema 将数据库结构导出为 DDL 脚本写入 w
+// 依次包含 CREATE DATABASE / STABLE / TABLE（含子表 TAG 值）/ STREAM / TOPIC / INDEX，
+// 每条语句一行、以分号结尾，表名均带库名前缀；语句尽量使用 IF NOT EXISTS 以便重复导入。
+// STREAM / TOPIC 为服务端记录的原始语句，其中的表名可能不带库名，
+// 因此脚本在 CREATE DATABASE 之后紧跟 USE <db>，须按顺序在同一连接上执行（ImportSchema 即如此）
+func (c *Client) ExportSchema(dbName string, w io.Writer) error {
+	db, err := sanitizeIdent(dbName)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	emit := func(stmt string) {
+		bw.WriteString(stmt)
+		bw.WriteString(";\n")
+	}
+	fmt.Fprintf(bw, "-- tdorm schema export: %s\n", db)
+
+	// 数据库
+	rows, err := c.queryMaps("SHOW CREATE DATABASE " + db)
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		emit(addIfNotExists(rowString(r, "Create Database", "create database"), "DATABASE"))
+	}
+	emit("USE " + db)
+
+	dbLit, _ := formatValue(db)
+	// 超级表
+	stables, err := c.queryMaps("SELECT stable_name FROM information_schema.ins_stables WHERE db_name = " + dbLit)
+	if err != nil {
+		return err
+	}
+	tagOrder := make(map[string][]ColumnInfo, len(stables))
+	for _, r := range stables {
+		st, err := sanitizeIdent(rowString(r, "stable_name"))
+		if err != nil {
+			return err
+		}
+		infos, err := c.describe(db + "." + st)
+		if err != nil {
+			return err
+		}
+		var cols, tags []ColumnDef
+		for _, info := range infos {
+			def := infoColumnDef(info)
+			if info.IsTag {
+				tags = append(tags, def)
+				tagOrder[st] = append(tagOrder[st], info)
+			} else {
+				cols = append(cols, def)
+			}
+		}
+		stmt, err := buildCreateStableSQL(db+"."+st, cols, tags)
+		if err != nil {
+			return err
+		}
+		emit(stmt)
+	}
+
+	// 普通表与子表
+	tables, err := c.queryMaps("SELECT table_name, stable_name, type FROM information_schema.ins_tables WHERE db_name = " + dbLit)
+	if err != nil {
+		return err
+	}
+	var subTables []string
+	subStable := map[string]string{}
+	for _, r := range tables {
+		tbl, err := sanitizeIdent(rowString(r, "table_name"))
+		if err != nil {
+			return err
+		}
+		if st := rowString(r, "stable_name"); st != "" {
+			subTables = append(subTables, tbl)
+			subStable[tbl] = st
+			continue
+		}
+		if !strings.EqualFold(rowString(r, "type"), "NORMAL_TABLE") {
+			continue
+		}
+		infos, err := c.describe(db + "." + tbl)
+		if err != nil {
+			return err
+		}
+		defs := make([]string, 0, len(infos))
+		for _, info := range infos {
+			col := infoColumnDef(info)
+			def := col.Name + " " + col.Type
+			if col.PrimaryKey {
+				def += " PRIMARY KEY"
+			}
+			colOpts, _ := columnOptions(col)
+			defs = append(defs, def+colOpts)
+		}
+		emit(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (%s)", db, tbl, strings.Join(defs, ", ")))
+	}
+	if len(subTables) > 0 {
+		tagRows, err := c.queryMaps("SELECT table_name, tag_name, tag_type, tag_value FROM information_schema.ins_tags WHERE db_name = " + dbLit)
+		if err != nil {
+			return err
+		}
+		tagVals := map[string]map[string]string{}
+		tagNull := map[string]map[string]bool{}
+		for _, r := range tagRows {
+			tbl := rowString(r, "table_name")
+			if tagVals[tbl] == nil {
+				tagVals[tbl] = map[string]string{}
+				tagNull[tbl] = map[string]bool{}
+			}
+			name := strings.ToLower(rowString(r, "tag_name"))
+			if r["tag_value"] == nil {
+				tagNull[tbl][name] = true
+				continue
+			}
+			tagVals[tbl][name] = rowString(r, "tag_value")
+		}
+		for _, tbl := range subTables {
+			st := subStable[tbl]
+			vals := make([]string, 0, len(tagOrder[st]))
+			for _, info := range tagOrder[st] {
+				name := strings.ToLower(info.Name)
+				v, ok := tagVals[tbl][name]
+				if !ok || tagNull[tbl][name] {
+					vals = append(vals, "NULL")
+					continue
+				}
+				lit, err := tagLiteral(info.Type, v)
+				if err != nil {
+					return fmt.Errorf("子表 %s 的 TAG %s 无法导出: %w", tbl, info.Name, err)
+				}
+				vals = append(vals, lit)
+			}
+			emit(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s USING %s.%s TAGS (%s)", db, tbl, db, st, strings.Join(vals, ", ")))
+		}
+	}
+
+	// 流计算、订阅主题与索引：直接导出服务端记录的原始语句
+	streams, err := c.queryMaps("SELECT * FROM information_schema.ins_streams")
+	if err != nil {
+		return err
+	}
+	for _, r := range streams {
+		if !strings.EqualFold(rowString(r, "source_db", "db_name"), db) {
+			continue
+		}
+		emit(addIfNotExists(strings.TrimRight(rowString(r, "sql"), "; \n"), "STREAM"))
+	}
+	topics, err := c.queryMaps("SELECT topic_name, sql FROM information_schema.ins_topics WHERE db_name = " + dbLit)
+	if err != nil {
+		return err
+	}
+	for _, r := range topics {
+		stmt := strings.TrimRight(rowString(r, "sql"), "; \n")
+		if !strings.HasPrefix(strings.ToUpper(stmt), "CREATE") {
+			stmt = fmt.Sprintf("CREATE TOPIC %s AS %s", rowString(r, "topic_name"), stmt)
+		}
+		emit(addIfNotExists(stmt, "TOPIC"))
+	}
+	indexes, err := c.queryMaps("SELECT * FROM information_schema.ins_indexes WHERE db_name = " + dbLit)
+	if err != nil {
+		return err
+	}
+	for _, r := range indexes {
+		if !strings.EqualFold(rowString(r, "index_type"), "tag") {
+			continue
+		}
+		emit(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s.%s ON %s.%s (%s)", db, rowString(r, "index_name"), db, rowString(r, "table_name"), rowString(r, "column_name")))
+	}
+	return bw.Flush()
+}
+
+// ImportSchema 逐条执行 DDL 脚本（ExportSchema 的输出），返回执行的语句数
+// 以 -- 开头的行视为注释；对象已存在的错误会被忽略，因此可重复导入。
+// 所有语句在同一连接上执行，使脚本中的 USE 对后续 STREAM / TOPIC 语句生效
+func (c *Client) ImportSchema(r io.Reader) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	conn, err := c.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	n := 0
+	for _, stmt := range splitStatements(string(data)) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			if isAlreadyExists(err) {
+				continue
+			}
+			return n, fmt.Errorf("执行失败 [%s]: %w", stmt, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ExportSchemaMsg 导出库结构并返回提示
+func (c *Client) ExportSchemaMsg(dbName string, w io.Writer) (string, error) {
+	if err := c.ExportSchema(dbName, w); err != nil {
+		return "", fmt.Errorf("ExportSchema %s failed: %w", dbName, err)
+	}
+	return fmt.Sprintf("库结构已导出: %s", dbName), nil
+}
+
+// ImportSchemaMsg 导入 DDL 脚本并返回提示
+func (c *Client) ImportSchemaMsg(r io.Reader) (string, error) {
+	n, err := c.ImportSchema(r)
+	if err != nil {
+		return "", fmt.Errorf("ImportSchema failed after %d statements: %w", n, err)
+	}
+	return fmt.Sprintf("库结构导入完成，执行 %d 条语句", n), nil
+}
+
+// queryMaps 执行任意查询并返回行列表（map）
+func (c *Client) queryMaps(sqlStr string) ([]map[string]interface{}, error) {
+	rows, err := c.DB.Query(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRowMaps(rows, nil)
+}
+
+// rowString 依次尝试多个列名（不区分大小写），返回第一个存在的列的字符串值
+func rowString(row map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := lookupTag(row, k); ok {
+			return asString(v)
+		}
+	}
+	return ""
+}
+
+// columnTypeDDL 将 DESCRIBE 的类型与长度还原为 DDL 类型，如 NCHAR + 64 -> NCHAR(64)
+func columnTypeDDL(info ColumnInfo) string {
+	typ := strings.ToUpper(strings.TrimSpace(info.Type))
+	if strings.Contains(typ, "(") {
+		return typ
+	}
+	switch typ {
+	case "BINARY", "VARCHAR", "NCHAR", "VARBINARY", "GEOMETRY":
+		return fmt.Sprintf("%s(%d)", typ, info.Length)
+	}
+	return typ
+}
+
+// tagLiteral 按 TAG 类型将 ins_tags 中的字符串值还原为 SQL 字面量
+func tagLiteral(typ string, v string) (string, error) {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.HasPrefix(typ, "JSON"):
+		return quoteJSON(v), nil
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "BINARY"),
+		strings.HasPrefix(typ, "GEOMETRY"), strings.HasPrefix(typ, "TIMESTAMP"):
+		return formatValue(v)
+	}
+	return v, nil
+}
+
+// addIfNotExists 为 CREATE <kind> 语句补充 IF NOT EXISTS
+func addIfNotExists(stmt string, kind string) string {
+	stmt = strings.TrimSpace(stmt)
+	prefix := "CREATE " + kind + " "
+	if len(stmt) < len(prefix) || !strings.EqualFold(stmt[:len(prefix)], prefix) {
+		return stmt
+	}
+	rest := strings.TrimSpace(stmt[len(prefix):])
+	if strings.HasPrefix(strings.ToUpper(rest), "IF NOT EXISTS") {
+		return stmt
+	}
+	return prefix + "IF NOT EXISTS " + rest
+}
+
+// splitStatements 按分号拆分脚本，忽略引号内的分号与 -- 注释行
+func splitStatements(script string) []string {
+	var stmts []string
+	var sb strings.Builder
+	var quote rune
+	lineStart := true
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if quote == 0 && lineStart && ch == '-' && i+1 < len(runes) && runes[i+1] == '-' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		lineStart = ch == '\n' || (lineStart && (ch == ' ' || ch == '\t' || ch == '\r'))
+		switch {
+		case quote != 0:
+			sb.WriteRune(ch)
+			if ch == '\\' && i+1 < len(runes) {
+				i++
+				sb.WriteRune(runes[i])
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			sb.WriteRune(ch)
+		case ch == ';':
+			if s := strings.TrimSpace(sb.String()); s != "" {
+				stmts = append(stmts, s)
+			}
+			sb.Reset()
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+	if s := strings.TrimSpace(sb.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// isAlreadyExists 判断错误是否为“对象已存在”
+func isAlreadyExists(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already exist")
+}
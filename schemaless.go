@@ -0,0 +1,313 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 无模式写入（InfluxDB 行协议、OpenTSDB telnet/JSON）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point InfluxDB 行协议中的一个数据点
+// 超级表与子表由 taosAdapter 按 measurement 与 tags 自动创建
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+	Time        time.Time // 为零值时由服务端使用当前时间
+}
+
+// NewPoint 创建数据点
+func NewPoint(measurement string) *Point {
+	return &Point{Measurement: measurement, Tags: map[string]string{}, Fields: map[string]interface{}{}}
+}
+
+// Tag 设置标签
+func (p *Point) Tag(key, value string) *Point {
+	p.Tags[key] = value
+	return p
+}
+
+// Field 设置字段，支持整数、浮点、布尔与字符串
+func (p *Point) Field(key string, value interface{}) *Point {
+	p.Fields[key] = value
+	return p
+}
+
+// At 设置时间戳
+func (p *Point) At(t time.Time) *Point {
+	p.Time = t
+	return p
+}
+
+// LineProtocol 编码为一行 InfluxDB 行协议，precision 取值：ns, u, ms, s, m, h
+// 标签与字段按键名排序，保证同一数据点编码结果稳定
+func (p *Point) LineProtocol(precision string) (string, error) {
+	if p.Measurement == "" {
+		return "", fmt.Errorf("measurement 不能为空")
+	}
+	if len(p.Fields) == 0 {
+		return "", fmt.Errorf("数据点 %s 没有字段", p.Measurement)
+	}
+	unit, err := precisionUnit(precision)
+	if err != nil {
+		return "", err
+	}
+	if err := lpCheckLine("measurement", p.Measurement); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(lpEscape(p.Measurement, ", "))
+	tagKeys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		if k == "" || p.Tags[k] == "" {
+			continue
+		}
+		if err := lpCheckLine("标签 "+k, k+p.Tags[k]); err != nil {
+			return "", err
+		}
+		sb.WriteString("," + lpEscape(k, ",= ") + "=" + lpEscape(p.Tags[k], ",= "))
+	}
+	fieldKeys := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for i, k := range fieldKeys {
+		if err := lpCheckLine("字段名", k); err != nil {
+			return "", err
+		}
+		fv, err := lpFieldValue(p.Fields[k])
+		if err != nil {
+			return "", fmt.Errorf("字段 %s: %w", k, err)
+		}
+		if i == 0 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(lpEscape(k, ",= ") + "=" + fv)
+	}
+	if !p.Time.IsZero() {
+		sb.WriteString(" " + strconv.FormatInt(p.Time.UnixNano()/int64(unit), 10))
+	}
+	return sb.String(), nil
+}
+
+// precisionUnit 行协议时间精度对应的时长
+func precisionUnit(precision string) (time.Duration, error) {
+	switch precision {
+	case "ns", "n", "":
+		return time.Nanosecond, nil
+	case "u", "us":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	return 0, fmt.Errorf("不支持的时间精度: %s", precision)
+}
+
+// lpCheckLine 行协议以换行分隔数据点且无法转义换行，含 \n、\r 的内容会被拒绝，避免注入额外的数据行
+func lpCheckLine(what, s string) error {
+	if strings.ContainsAny(s, "\r\n") {
+		return fmt.Errorf("%s 不能包含换行符: %q", what, s)
+	}
+	return nil
+}
+
+// lpEscape 按行协议规则用反斜杠转义指定字符
+func lpEscape(s string, chars string) string {
+	if !strings.ContainsAny(s, chars+"\\") {
+		return s
+	}
+	var sb strings.Builder
+	for _, ch := range s {
+		if ch == '\\' || strings.ContainsRune(chars, ch) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// lpFieldValue 编码字段值：整数加 i 后缀、无符号整数加 u 后缀、字符串加双引号
+func lpFieldValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case string:
+		if err := lpCheckLine("字符串字段", val); err != nil {
+			return "", err
+		}
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val) + `"`, nil
+	case bool:
+		if val {
+			return "t", nil
+		}
+		return "f", nil
+	case float32:
+		return lpFloat(float64(val))
+	case float64:
+		return lpFloat(val)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10) + "i", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10) + "u", nil
+	}
+	return "", fmt.Errorf("不支持的字段类型: %T", v)
+}
+
+func lpFloat(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("行协议不支持 NaN/Inf")
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
+
+// WritePoints 以 InfluxDB 行协议写入数据点；db 为空时使用 DSN 中的库
+func (c *Client) WritePoints(db string, points []*Point, precision string) error {
+	lines := make([]string, 0, len(points))
+	for _, p := range points {
+		line, err := p.LineProtocol(precision)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	return c.WriteLineProtocol(db, strings.Join(lines, "\n"), precision)
+}
+
+// WriteLineProtocol 通过 taosAdapter 的 /influxdb/v1/write 写入行协议文本
+func (c *Client) WriteLineProtocol(db string, lines string, precision string) error {
+	if _, err := precisionUnit(precision); err != nil {
+		return err
+	}
+	if precision == "" {
+		precision = "ns"
+	}
+	dbName, err := c.schemalessDB(db)
+	if err != nil {
+		return err
+	}
+	q := url.Values{"db": {dbName}, "precision": {precision}}
+	return c.postAdapter("/influxdb/v1/write", q, "text/plain", []byte(lines))
+}
+
+// WriteOpenTSDBTelnet 通过 /opentsdb/v1/put/telnet 写入 OpenTSDB telnet 格式，每个元素一行
+// 例如："meters.current 1648432611249 10.3 location=California.SanFrancisco groupid=2"
+func (c *Client) WriteOpenTSDBTelnet(db string, lines []string) error {
+	dbName, err := c.schemalessDB(db)
+	if err != nil {
+		return err
+	}
+	body := strings.Join(lines, "\n")
+	return c.postAdapter("/opentsdb/v1/put/telnet/"+dbName, nil, "text/plain", []byte(body))
+}
+
+// WriteOpenTSDBJSON 通过 /opentsdb/v1/put/json 写入 OpenTSDB JSON 格式（单个对象或数组）
+func (c *Client) WriteOpenTSDBJSON(db string, payload []byte) error {
+	dbName, err := c.schemalessDB(db)
+	if err != nil {
+		return err
+	}
+	return c.postAdapter("/opentsdb/v1/put/json/"+dbName, nil, "application/json", payload)
+}
+
+// schemalessDB 校验库名，为空时取 DSN 中的库
+func (c *Client) schemalessDB(db string) (string, error) {
+	if db == "" && c.restCfg != nil {
+		db = c.restCfg.DbName
+	}
+	if db == "" {
+		return "", fmt.Errorf("无模式写入需要指定数据库")
+	}
+	return sanitizeIdent(db)
+}
+
+// postAdapter 以 DSN 中的账号向 taosAdapter 发送 POST 请求
+func (c *Client) postAdapter(path string, q url.Values, contentType string, body []byte) error {
+	cfg := c.restCfg
+	if cfg == nil {
+		return fmt.Errorf("无模式写入需要通过 NewClient 创建客户端")
+	}
+	scheme := cfg.Net
+	if scheme == "" {
+		scheme = "http"
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 6041
+	}
+	if cfg.Token != "" {
+		if q == nil {
+			q = url.Values{}
+		}
+		q.Set("token", cfg.Token)
+	}
+	u := url.URL{Scheme: scheme, Host: fmt.Sprintf("%s:%d", cfg.Addr, port), Path: path, RawQuery: q.Encode()}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if cfg.User != "" {
+		req.SetBasicAuth(cfg.User, cfg.Passwd)
+	}
+	resp, err := c.adapterHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("taosAdapter %s 返回 %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	// OpenTSDB 等接口在 HTTP 200 时仍可能通过 code 字段返回错误
+	var ret struct {
+		Code *int   `json:"code"`
+		Desc string `json:"desc"`
+	}
+	if json.Unmarshal(msg, &ret) == nil && ret.Code != nil && *ret.Code != 0 {
+		return fmt.Errorf("taosAdapter %s 写入失败 [%d]: %s", path, *ret.Code, ret.Desc)
+	}
+	return nil
+}
+
+func (c *Client) adapterHTTPClient() *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.httpClient == nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		if c.restCfg != nil && c.restCfg.SkipVerify {
+			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		c.httpClient = &http.Client{Transport: tr, Timeout: 30 * time.Second}
+	}
+	return c.httpClient
+}
@@ -0,0 +1,409 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 本地落盘的存储转发队列（断网缓存、恢复后按序重放）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSpoolFull 落盘队列达到 MaxBytes，新数据未被保存
+var ErrSpoolFull = errors.New("spool 已满")
+
+// SpoolOptions 存储转发队列配置
+type SpoolOptions struct {
+	Dir           string                       // 队列文件目录，必填
+	MaxBytes      int64                        // 队列文件总大小上限，默认 1GB
+	SegmentBytes  int64                        // 单个队列文件大小，超过后切换新文件，默认 16MB
+	RetryInterval time.Duration                // 断网时的重放间隔，默认 5s
+	OnDrop        func(err error, stmt string) // 重放时因语句内容被服务端拒绝（语法、类型错误等）而丢弃的语句
+	MaxAttempts   int                          // 同一条记录被服务端连续拒绝的重放次数上限，超过后交给 OnDrop 丢弃，默认 60；连接类错误不计数
+}
+
+// Spool 存储转发队列：写入失败（网络、服务不可用）的数据追加到本地文件，
+// 连接恢复后由后台按写入顺序重放；进程重启后继续重放未完成的文件。
+// 队列非空期间的新数据同样先落盘，以保证顺序；并发调用 Write 之间不保证先后。
+// 落盘时未提供 ts 的行会按客户端时钟分配时间戳，重放结果与原始写入时间一致；
+// 进程在重放中途退出时，部分语句可能被重复执行，相同 ts 的行会被覆盖而不会重复。
+type Spool struct {
+	c    *Client
+	opts SpoolOptions
+
+	mu       sync.Mutex
+	segments []int64  // 按顺序排列的队列文件序号，最后一个可能是当前追加文件
+	active   *os.File // 当前追加文件，nil 表示需新建
+	activeSz int64
+	size     int64 // 所有队列文件的总字节数
+	headDone int   // 首个文件中已重放的记录数
+
+	headAttempts int // 当前首条记录被服务端拒绝的次数，由 replayMu 保护
+
+	replayMu sync.Mutex // 保证同一时刻只有一个重放过程
+
+	kick chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+const spoolFileExt = ".spool"
+
+// OpenSpool 打开（或创建）存储转发队列并启动后台重放，使用完毕需调用 Close
+func (c *Client) OpenSpool(opts SpoolOptions) (*Spool, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("spool 目录不能为空")
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 30
+	}
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = 16 << 20
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 60
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Spool{c: c, opts: opts, kick: make(chan struct{}, 1), done: make(chan struct{})}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go s.loop()
+	s.notify()
+	return s, nil
+}
+
+// load 扫描目录中已有的队列文件，截断末尾未写完整的记录
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var seq int64
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolFileExt) {
+			continue
+		}
+		if _, err := fmt.Sscanf(e.Name(), "%d"+spoolFileExt, &seq); err != nil {
+			continue
+		}
+		s.segments = append(s.segments, seq)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+	for _, seq := range s.segments {
+		path := s.segmentPath(seq)
+		_, valid, err := readSpoolRecords(path)
+		if err != nil {
+			return err
+		}
+		if fi, err := os.Stat(path); err == nil && fi.Size() > valid {
+			if err := os.Truncate(path, valid); err != nil {
+				return err
+			}
+		}
+		s.size += valid
+	}
+	return nil
+}
+
+func (s *Spool) segmentPath(seq int64) string {
+	return filepath.Join(s.opts.Dir, fmt.Sprintf("%020d%s", seq, spoolFileExt))
+}
+
+// Insert 写入一行；连接不可用时落盘，稍后重放
+func (s *Spool) Insert(table string, row map[string]interface{}) error {
+	return s.Write(WriteRow{Table: table, Row: row})
+}
+
+// Write 写入若干行。队列为空时直接写入，遇到网络、服务端暂时不可用等可重试错误时落盘；
+// 队列非空时直接落盘。因语句内容被服务端拒绝的数据（语法、类型错误等）不会落盘，错误直接返回。
+func (s *Spool) Write(rows ...WriteRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tables, _ := groupWriteRows(rows)
+	stmts, err := s.c.renderSpoolStatements(tables)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, stmt := range stmts {
+		// 只在检查队列与追加时持锁，网络请求期间不阻塞其他写入者与重放
+		s.mu.Lock()
+		empty := s.size == 0
+		s.mu.Unlock()
+		if empty {
+			_, err := s.c.DB.Exec(stmt)
+			if err == nil {
+				continue
+			}
+			if isPermanentError(err) {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		s.mu.Lock()
+		err := s.appendLocked(stmts[i:])
+		s.mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		s.notify()
+		break
+	}
+	return errors.Join(errs...)
+}
+
+// renderSpoolStatements 将数据拼装为可重放的 INSERT 语句，缺少 ts 的行按客户端时钟固定时间戳
+func (c *Client) renderSpoolStatements(tables []TableRows) ([]string, error) {
+	tables, err := c.validateTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	opts := c.batchOpts
+	policy := c.tsPolicy
+	c.mu.RUnlock()
+	if policy != TimestampRequired {
+		policy = TimestampClientClock
+	}
+	segs, err := buildInsertSegments(tables, opts.MissingColumns, func(table string) tsFiller {
+		return newTSFiller(policy, table, &c.tsClock)
+	}, c.codec())
+	if err != nil || len(segs) == 0 {
+		return nil, err
+	}
+	budget := opts.MaxSQLBytes
+	if budget <= 0 {
+		budget = c.maxSQLLength()
+	}
+	chunks, err := packInsertChunks(segs, budget)
+	if err != nil {
+		return nil, err
+	}
+	stmts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		stmts[i] = ch.sql
+	}
+	return stmts, nil
+}
+
+// appendLocked 追加语句到当前队列文件并同步到磁盘；超出 MaxBytes 时整体放弃并返回 ErrSpoolFull
+func (s *Spool) appendLocked(stmts []string) error {
+	var need int64
+	for _, st := range stmts {
+		need += int64(len(st)) + spoolRecordHeader
+	}
+	if s.size+need > s.opts.MaxBytes {
+		return ErrSpoolFull
+	}
+	for _, st := range stmts {
+		if s.active == nil || s.activeSz >= s.opts.SegmentBytes {
+			if err := s.rollLocked(); err != nil {
+				return err
+			}
+		}
+		n, err := s.active.Write(encodeSpoolRecord([]byte(st)))
+		s.activeSz += int64(n)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return s.active.Sync()
+}
+
+// rollLocked 关闭当前追加文件并新建下一个
+func (s *Spool) rollLocked() error {
+	if err := s.closeActiveLocked(); err != nil {
+		return err
+	}
+	var seq int64 = 1
+	if n := len(s.segments); n > 0 {
+		seq = s.segments[n-1] + 1
+	}
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.segments = append(s.segments, seq)
+	s.active = f
+	s.activeSz = 0
+	return nil
+}
+
+func (s *Spool) closeActiveLocked() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// Pending 返回队列中待重放的字节数
+func (s *Spool) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Flush 立即尝试重放队列，返回首个导致停止的错误（连接仍不可用时）
+func (s *Spool) Flush() error {
+	return s.replay()
+}
+
+// Close 停止后台重放并关闭文件，未重放的数据保留到下次 OpenSpool
+func (s *Spool) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeActiveLocked()
+}
+
+func (s *Spool) notify() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Spool) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.opts.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.kick:
+		case <-ticker.C:
+		}
+		s.replay()
+	}
+}
+
+// replay 按顺序重放队列文件，逐个删除已完成的文件
+func (s *Spool) replay() error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		seq := s.segments[0]
+		if len(s.segments) == 1 && s.active != nil {
+			// 只剩当前追加文件时先封存，之后的写入进入新文件
+			if err := s.closeActiveLocked(); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+		}
+		done := s.headDone
+		s.mu.Unlock()
+
+		path := s.segmentPath(seq)
+		records, valid, err := readSpoolRecords(path)
+		if err != nil {
+			return err
+		}
+		for i := done; i < len(records); i++ {
+			stmt := string(records[i])
+			if _, err := s.c.DB.Exec(stmt); err != nil {
+				if !isPermanentError(err) {
+					// 未识别的服务端拒绝同样可能是坏数据，连续失败达到上限后丢弃，避免阻塞后续记录
+					if isUnavailableError(err) {
+						return err
+					}
+					if s.headAttempts++; s.headAttempts < s.opts.MaxAttempts {
+						return err
+					}
+				}
+				if s.opts.OnDrop != nil {
+					s.opts.OnDrop(err, stmt)
+				}
+			}
+			s.headAttempts = 0
+			s.mu.Lock()
+			s.headDone = i + 1
+			s.mu.Unlock()
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.mu.Lock()
+		s.segments = s.segments[1:]
+		s.headDone = 0
+		s.size -= valid
+		s.mu.Unlock()
+	}
+}
+
+// spoolRecordHeader 记录头：4 字节长度 + 4 字节 CRC32（小端）
+const spoolRecordHeader = 8
+
+func encodeSpoolRecord(payload []byte) []byte {
+	buf := make([]byte, spoolRecordHeader+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[spoolRecordHeader:], payload)
+	return buf
+}
+
+// readSpoolRecords 读取队列文件中的完整记录，返回有效部分的字节数；遇到不完整或校验失败的记录即停止
+func readSpoolRecords(path string) ([][]byte, int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	records, valid := decodeSpoolRecords(data)
+	return records, valid, nil
+}
+
+func decodeSpoolRecords(data []byte) ([][]byte, int64) {
+	var records [][]byte
+	var off int64
+	for int64(len(data))-off >= spoolRecordHeader {
+		n := int64(binary.LittleEndian.Uint32(data[off : off+4]))
+		sum := binary.LittleEndian.Uint32(data[off+4 : off+8])
+		end := off + spoolRecordHeader + n
+		if end > int64(len(data)) {
+			break
+		}
+		payload := data[off+spoolRecordHeader : end]
+		if crc32.ChecksumIEEE(payload) != sum {
+			break
+		}
+		records = append(records, payload)
+		off = end
+	}
+	return records, off
+}
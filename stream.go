@@ -53,13 +53,7 @@ func (c *Client) CreateStream(def StreamDef) error {
 	}
 	if def.Watermark > 0 {
 		// TDengine 时间格式通常为 10s, 500ms 等
-		// 这里简单处理：如果整秒则用s，否则ms
-		ms := def.Watermark.Milliseconds()
-		if ms%1000 == 0 {
-			sb.WriteString(fmt.Sprintf("WATERMARK %ds ", ms/1000))
-		} else {
-			sb.WriteString(fmt.Sprintf("WATERMARK %dms ", ms))
-		}
+		sb.WriteString("WATERMARK " + formatDurationLiteral(def.Watermark) + " ")
 	}
 	
 	for _, opt := range def.OtherOptions {
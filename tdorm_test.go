@@ -11,9 +11,22 @@
 package tdorm
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/taosdata/driver-go/v3/common/param"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
 )
 
 func TestSanitizeIdent(t *testing.T) {
@@ -55,7 +68,7 @@ func TestFormatValue(t *testing.T) {
 func TestFilterBuildWhere_InBetweenOrderLimit(t *testing.T) {
 	// IN
 	f := Filter{Conditions: []Condition{{Column: "voltage", Op: "IN", Value: []interface{}{218, 219}}}, Conj: "AND"}
-	where, err := f.buildWhere()
+	where, err := f.buildWhere(nil)
 	if err != nil {
 		t.Fatalf("buildWhere error: %v", err)
 	}
@@ -67,7 +80,7 @@ func TestFilterBuildWhere_InBetweenOrderLimit(t *testing.T) {
 	t1 := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
 	t2 := time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC)
 	f2 := Filter{Conditions: []Condition{{Column: "ts", Op: "BETWEEN", Value: [2]interface{}{t1, t2}}}}
-	where2, err := f2.buildWhere()
+	where2, err := f2.buildWhere(nil)
 	if err != nil {
 		t.Fatalf("buildWhere error: %v", err)
 	}
@@ -188,3 +201,1515 @@ func TestClientLifecycle_Optional(t *testing.T) {
 		t.Log(msg)
 	}
 }
+
+func TestSubTableNamers(t *testing.T) {
+	tags := map[string]interface{}{"device_id": "dev-001", "location": "roomA"}
+	name, err := TemplateNamer("meter_{device_id}")("meters", tags)
+	if err != nil || name != "meter_dev_001_af844599" {
+		t.Fatalf("template namer: %s err=%v", name, err)
+	}
+	seen := map[string]string{}
+	for _, v := range []string{"a-b", "a.b", "a b", "a_b"} {
+		name, err := TemplateNamer("m_{id}")("meters", map[string]interface{}{"id": v})
+		if err != nil {
+			t.Fatalf("template namer %q: %v", v, err)
+		}
+		if prev, ok := seen[name]; ok {
+			t.Fatalf("%q and %q both named %s", prev, v, name)
+		}
+		seen[name] = v
+	}
+	if name, _ := TemplateNamer("m_{id}")("meters", map[string]interface{}{"id": "a_b"}); name != "m_a_b" {
+		t.Fatalf("valid value should be kept: %s", name)
+	}
+	if _, err := TemplateNamer("meter_{missing}")("meters", tags); err == nil {
+		t.Fatalf("expected error for missing tag")
+	}
+	// time.Now() 带单调时钟读数与时区，不同服务须得到相同的名字
+	now := time.Now()
+	local := map[string]interface{}{"at": now, "n": 1.5}
+	remote := map[string]interface{}{"at": now.Round(0).In(time.FixedZone("X", 3600)), "n": float64(1.5)}
+	for _, namer := range []SubTableNamer{TemplateNamer("m_{at}_{n}"), HashNamer("")} {
+		a, err1 := namer("meters", local)
+		b, err2 := namer("meters", remote)
+		if err1 != nil || err2 != nil || a != b {
+			t.Fatalf("expected same name for same time tag: %s %s %v %v", a, b, err1, err2)
+		}
+	}
+	if s := tagString(time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)); s != "2026-01-02T03:04:05.000000006Z" {
+		t.Fatalf("unexpected time tag string: %s", s)
+	}
+	c := &Client{}
+	if err := c.SetSubTableNamer("meters", HashNamer(""), []string{"location", "device_id"}); err != nil {
+		t.Fatal(err)
+	}
+	dup := map[string]interface{}{"location": "roomA", "Location": "roomB"}
+	if _, err := c.SubTableName("meters", dup); err == nil {
+		t.Fatalf("expected error for tag keys differing only in case")
+	}
+	if _, err := c.orderedTagValues("meters", dup); err == nil {
+		t.Fatalf("expected error for tag keys differing only in case")
+	}
+	if vals, err := c.orderedTagValues("meters", map[string]interface{}{"LOCATION": "roomA"}); err != nil || vals[0] != "roomA" || vals[1] != nil {
+		t.Fatalf("unexpected tag values: %v %v", vals, err)
+	}
+	h1, err := HashNamer("")("meters", tags)
+	if err != nil {
+		t.Fatalf("hash namer: %v", err)
+	}
+	h2, _ := HashNamer("")("METERS", map[string]interface{}{"LOCATION": "roomA", "device_id": "dev-001"})
+	if h1 != h2 || len(h1) != len("t_")+32 {
+		t.Fatalf("hash namer not stable: %s vs %s", h1, h2)
+	}
+}
+
+func TestJSONTagSupport(t *testing.T) {
+	v, err := (*valueCodec)(nil).formatTagValue(map[string]interface{}{"k": `a'b\c`})
+	if err != nil || v != `'{"k":"a\'b\\\\c"}'` {
+		t.Fatalf("json tag format: %s err=%v", v, err)
+	}
+	if err := validateJSONTags([]ColumnDef{{Name: "v", Type: "INT"}}, []ColumnDef{{Name: "info", Type: "JSON"}, {Name: "g", Type: "INT"}}); err == nil {
+		t.Fatalf("expected error for JSON tag with other tags")
+	}
+	f := Filter{Conditions: []Condition{
+		{Column: "info", JSONKey: "k1", Op: ">", Value: 3},
+		{Column: "info", Op: "CONTAINS", Value: "k2"},
+		{Column: "info", Op: "?", Value: "k3"},
+	}}
+	where, err := f.buildWhere(nil)
+	if err != nil {
+		t.Fatalf("buildWhere error: %v", err)
+	}
+	if where != "WHERE info->'k1' > 3 AND info CONTAINS 'k2' AND info CONTAINS 'k3'" {
+		t.Fatalf("unexpected json where: %s", where)
+	}
+	m, ok := decodeJSONValue([]byte(`{"k":1}`)).(map[string]interface{})
+	if !ok || m["k"] != float64(1) {
+		t.Fatalf("json decode failed: %v", m)
+	}
+}
+
+func TestSchemaScriptHelpers(t *testing.T) {
+	script := "-- header\nCREATE DATABASE IF NOT EXISTS db;\nCREATE TABLE IF NOT EXISTS db.d1 USING db.m TAGS ('a;b', 'it''s');\n"
+	stmts := splitStatements(script)
+	if len(stmts) != 2 || stmts[1] != "CREATE TABLE IF NOT EXISTS db.d1 USING db.m TAGS ('a;b', 'it''s')" {
+		t.Fatalf("unexpected statements: %q", stmts)
+	}
+	if s := addIfNotExists("create stream s1 into t as select 1", "STREAM"); s != "CREATE STREAM IF NOT EXISTS s1 into t as select 1" {
+		t.Fatalf("unexpected if not exists: %s", s)
+	}
+	if s := columnTypeDDL(ColumnInfo{Type: "NCHAR", Length: 64}); s != "NCHAR(64)" {
+		t.Fatalf("unexpected type ddl: %s", s)
+	}
+}
+
+func TestTableOptions(t *testing.T) {
+	clause, err := buildTableOptions([]TableOptions{{Comment: "test device", TTL: TTLDays(7)}}, false)
+	if err != nil || clause != " COMMENT 'test device' TTL 7" {
+		t.Fatalf("unexpected sub table options: %q err=%v", clause, err)
+	}
+	sqlStr, err := buildCreateStableSQL("meters", []ColumnDef{{Name: "current", Type: "FLOAT"}}, []ColumnDef{{Name: "location", Type: "NCHAR(64)"}},
+		TableOptions{Comment: "meters", MaxDelay: 5 * time.Second, Rollup: "AVG"})
+	if err != nil {
+		t.Fatalf("build create stable: %v", err)
+	}
+	if sqlStr != "CREATE STABLE IF NOT EXISTS meters (ts TIMESTAMP, current FLOAT) TAGS (location NCHAR(64)) COMMENT 'meters' MAX_DELAY 5s ROLLUP(avg)" {
+		t.Fatalf("unexpected create stable: %s", sqlStr)
+	}
+	if _, err := buildTableOptions([]TableOptions{{TTL: TTLDays(1)}}, true); err == nil {
+		t.Fatalf("expected error for TTL on stable")
+	}
+	if clause, err := buildTableOptions([]TableOptions{{TTL: TTLDays(0)}}, false); err != nil || clause != " TTL 0" {
+		t.Fatalf("expected explicit TTL 0: %q err=%v", clause, err)
+	}
+	if clause, _ := buildTableOptions([]TableOptions{{Comment: "x"}}, false); clause != " COMMENT 'x'" {
+		t.Fatalf("unset TTL should be omitted: %q", clause)
+	}
+	if _, err := buildCreateStableSQL("meters", []ColumnDef{{Name: "current", Type: "FLOAT"}}, nil, TableOptions{Comment: "a"}, TableOptions{Rollup: "avg"}); err == nil {
+		t.Fatalf("expected error for multiple options")
+	}
+}
+
+func TestSubTableCacheLRU(t *testing.T) {
+	sc := newSubTableCache(SubTableCacheOptions{MaxEntries: 2, TTL: time.Hour})
+	if sc.hit("d1", "meters", "'a'") {
+		t.Fatalf("expected miss on empty cache")
+	}
+	sc.add("d1", "meters", "'a'")
+	sc.add("d2", "meters", "'b'")
+	if !sc.hit("D1", "meters", "'a'") {
+		t.Fatalf("expected hit for d1")
+	}
+	if sc.hit("d1", "meters", "'changed'") {
+		t.Fatalf("expected miss when tags differ")
+	}
+	sc.add("d3", "meters", "'c'") // 淘汰最久未使用的 d2
+	if sc.hit("d2", "meters", "'b'") {
+		t.Fatalf("expected d2 evicted")
+	}
+	sc.removeStable("meters")
+	st := sc.snapshot()
+	if st.Size != 0 || st.Hits != 1 || st.Evictions != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestBuildInsertBody(t *testing.T) {
+	ts := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	body, err := buildInsertBody([]map[string]interface{}{{"ts": ts, "current": 1.5}}, nil, nil)
+	if err != nil || body != "(ts, current) VALUES ('2024-10-01 00:00:00.000', 1.5)" {
+		t.Fatalf("unexpected insert body: %s err=%v", body, err)
+	}
+	if _, err := buildInsertBody([]map[string]interface{}{{"bad-col": 1}}, nil, nil); err == nil {
+		t.Fatalf("expected error for illegal column")
+	}
+	tags, err := (*valueCodec)(nil).formatTagValues([]interface{}{"roomA", 2})
+	if err != nil || tags != "'roomA', 2" {
+		t.Fatalf("unexpected tags: %s err=%v", tags, err)
+	}
+}
+
+func TestPackInsertChunks(t *testing.T) {
+	segs, err := buildInsertSegments([]TableRows{
+		{Table: "d1", Rows: []map[string]interface{}{{"current": 1}}},
+		{Table: "d2", Stable: "meters", Tags: []interface{}{"roomB"}, Rows: []map[string]interface{}{{"current": 2}}},
+		{Table: "d3"},
+	}, MissingUntouched, nil, nil)
+	if err != nil {
+		t.Fatalf("build segments: %v", err)
+	}
+	chunks, err := packInsertChunks(segs, 1<<20)
+	if err != nil || len(chunks) != 1 {
+		t.Fatalf("pack chunks: %v err=%v", chunks, err)
+	}
+	expected := "INSERT INTO d1 (ts, current) VALUES (NOW(), 1) d2 USING meters TAGS ('roomB') (ts, current) VALUES (NOW(), 2)"
+	if chunks[0].sql != expected {
+		t.Fatalf("unexpected multi insert: %s", chunks[0].sql)
+	}
+
+	// 按字节上限拆分：每条语句最多容纳两行
+	rows := make([]map[string]interface{}, 5)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"current": i}
+	}
+	segs, _ = buildInsertSegments([]TableRows{{Table: "d1", Rows: rows}}, MissingUntouched, nil, nil)
+	budget := len("INSERT INTO d1 (ts, current) VALUES (NOW(), 0) (NOW(), 1)")
+	chunks, err = packInsertChunks(segs, budget)
+	if err != nil || len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d err=%v", len(chunks), err)
+	}
+	if chunks[1].indexes[0] != 2 || chunks[1].rows != 2 || chunks[2].rows != 1 {
+		t.Fatalf("unexpected chunk layout: %+v", chunks)
+	}
+	for _, ch := range chunks {
+		if len(ch.sql) > budget {
+			t.Fatalf("chunk exceeds budget: %s", ch.sql)
+		}
+	}
+	if _, err := packInsertChunks(segs, 10); err == nil {
+		t.Fatalf("expected error when a single row exceeds budget")
+	}
+}
+
+func TestGroupWriteRows(t *testing.T) {
+	tables, order := groupWriteRows([]WriteRow{
+		{Table: "d1", Row: map[string]interface{}{"current": 1}},
+		{Table: "d2", Stable: "meters", Tags: []interface{}{"roomB"}, Row: map[string]interface{}{"current": 2}},
+		{Table: "D1", Row: map[string]interface{}{"current": 3}},
+	})
+	if len(tables) != 2 || len(tables[0].Rows) != 2 || tables[1].Stable != "meters" || order[1] != 2 {
+		t.Fatalf("unexpected grouping: %+v", tables)
+	}
+	if estimateRowSize(WriteRow{Table: "d1", Row: map[string]interface{}{"name": "abc"}}) <= 0 {
+		t.Fatalf("expected positive row size")
+	}
+}
+
+func TestPointLineProtocol(t *testing.T) {
+	p := NewPoint("meters").Tag("location", "San Francisco").Tag("group,id", "a=b").
+		Field("current", 10.3).Field("voltage", 219).Field("note", `say "hi"`).Field("ok", true).
+		At(time.Unix(1648432611, 249000000))
+	line, err := p.LineProtocol("ms")
+	if err != nil {
+		t.Fatalf("line protocol: %v", err)
+	}
+	expected := `meters,group\,id=a\=b,location=San\ Francisco current=10.3,note="say \"hi\"",ok=t,voltage=219i 1648432611249`
+	if line != expected {
+		t.Fatalf("unexpected line:\n%s\n%s", line, expected)
+	}
+	if _, err := NewPoint("m").Field("v", math.NaN()).LineProtocol("ms"); err == nil {
+		t.Fatalf("expected error for NaN field")
+	}
+	if _, err := NewPoint("m").LineProtocol("ms"); err == nil {
+		t.Fatalf("expected error for point without fields")
+	}
+	for _, p := range []*Point{
+		NewPoint("m").Tag("location", "a\nm v=1i").Field("v", 1),
+		NewPoint("m").Field("note", "x\"\r\nm v=1i"),
+		NewPoint("m\n").Field("v", 1),
+		NewPoint("m").Field("v\n", 1),
+	} {
+		if line, err := p.LineProtocol("ms"); err == nil {
+			t.Fatalf("expected newline rejected, got %q", line)
+		}
+	}
+}
+
+func TestPreparedInsertBinding(t *testing.T) {
+	infos := []ColumnInfo{
+		{Name: "ts", Type: "TIMESTAMP", Length: 8},
+		{Name: "current", Type: "FLOAT", Length: 4},
+		{Name: "voltage", Type: "INT", Length: 4},
+		{Name: "location", Type: "VARCHAR", Length: 64, IsTag: true},
+	}
+	cols, tags, err := selectBindColumns(infos, []string{"voltage"})
+	if err != nil || len(cols) != 2 || len(tags) != 1 {
+		t.Fatalf("select bind columns: %v %v err=%v", cols, tags, err)
+	}
+	if s := buildPreparedInsertSQL("meters", cols, tags); s != "INSERT INTO ? USING meters TAGS (?) (ts, voltage) VALUES (?, ?)" {
+		t.Fatalf("unexpected prepared sql: %s", s)
+	}
+	fill := (&PreparedInserter{c: &Client{}}).tsFiller("d1")
+	params, _, err := bindColumns(cols, []map[string]interface{}{{"voltage": 220}, {}}, 0, fill)
+	if err != nil || len(params) != 2 || len(params[1].GetValues()) != 2 {
+		t.Fatalf("bind columns: %v err=%v", params, err)
+	}
+	if _, _, err := bindColumns(cols, []map[string]interface{}{{"voltage": "x"}}, 0, fill); err == nil {
+		t.Fatalf("expected type error when binding string to INT")
+	}
+	// 时间戳与 BatchInsert 一样接受整数与字符串
+	want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, ts := range []interface{}{want, want.UnixMilli(), "2026-01-02T03:04:05Z"} {
+		if got, err := bindTime(ts, 0); err != nil || !got.Equal(want) {
+			t.Fatalf("bind time %v: %v %v", ts, got, err)
+		}
+	}
+	if _, err := bindTime(1.5, 0); err == nil {
+		t.Fatalf("expected error for float timestamp")
+	}
+	jp := param.NewParam(1)
+	if err := setBindValue(jp, 0, ColumnInfo{Name: "info", Type: "JSON"}, map[string]interface{}{"k": 1}, 0); err != nil || fmt.Sprintf("%s", jp.GetValues()[0]) != `{"k":1}` {
+		t.Fatalf("expected map marshalled for JSON tag: %v %v", jp.GetValues(), err)
+	}
+	if err := setBindValue(param.NewParam(1), 0, ColumnInfo{Name: "d", Type: "DECIMAL(10, 2)"}, "1.5", 0); err == nil {
+		t.Fatalf("expected error for unsupported DECIMAL binding")
+	}
+	if _, _, err := bindColumns([]ColumnInfo{{Name: "ts", Type: "TIMESTAMP"}, {Name: "d", Type: "DECIMAL(10, 2)"}}, nil, 0, fill); err == nil {
+		t.Fatalf("expected error for unsupported DECIMAL column")
+	}
+}
+
+func TestSparseRowGrouping(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"ts": 1, "current": 1.1},
+		{"ts": 2, "current": 1.2, "voltage": 220},
+		{"ts": 3, "current": 1.3},
+	}
+	segs, err := buildInsertSegments([]TableRows{{Table: "d1", Rows: rows}}, MissingUntouched, nil, nil)
+	if err != nil || len(segs) != 2 {
+		t.Fatalf("expected 2 segments, got %d err=%v", len(segs), err)
+	}
+	if segs[0].header != "d1 (ts, current) VALUES" || len(segs[0].indexes) != 2 || segs[0].indexes[1] != 2 {
+		t.Fatalf("unexpected first segment: %+v", segs[0])
+	}
+	segs, _ = buildInsertSegments([]TableRows{{Table: "d1", Rows: rows}}, MissingAsNull, nil, nil)
+	if len(segs) != 1 || segs[0].groups[0] != "(1, 1.1, NULL)" {
+		t.Fatalf("unexpected null-filled segment: %+v", segs)
+	}
+}
+
+func TestTimestampPolicies(t *testing.T) {
+	rows := []map[string]interface{}{{"current": 1}, {"current": 2, "voltage": 3}, {"current": 4}}
+	segs, err := buildInsertSegments([]TableRows{{Table: "d1", Rows: rows}}, MissingUntouched,
+		func(table string) tsFiller { return newTSFiller(TimestampServerOffset, table, nil) }, nil)
+	if err != nil || len(segs) != 2 {
+		t.Fatalf("unexpected segments: %+v err=%v", segs, err)
+	}
+	// 偏移按输入顺序分配，与分组无关
+	if segs[0].groups[0] != "(NOW() + 0a, 1)" || segs[0].groups[1] != "(NOW() + 2a, 4)" || !strings.HasPrefix(segs[1].groups[0], "(NOW() + 1a, ") {
+		t.Fatalf("unexpected offsets: %v %v", segs[0].groups, segs[1].groups)
+	}
+	var shared tsClock
+	segs, err = buildInsertSegments([]TableRows{{Table: "d1", Rows: rows}}, MissingUntouched,
+		func(table string) tsFiller { return newTSFiller(TimestampClientClock, table, &shared) }, nil)
+	if err != nil || len(segs) != 2 {
+		t.Fatalf("unexpected segments: %+v err=%v", segs, err)
+	}
+	tsOf := func(group string) string { return strings.SplitN(group[1:], ", ", 2)[0] }
+	if t0, t1, t2 := tsOf(segs[0].groups[0]), tsOf(segs[1].groups[0]), tsOf(segs[0].groups[1]); !(t0 < t1 && t1 < t2) {
+		t.Fatalf("client clock not in input order: %s %s %s", t0, t1, t2)
+	}
+
+	var clock tsClock
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a, b := clock.next("d1", now), clock.next("D1", now)
+	if !b.After(a) || b.Sub(a) != time.Millisecond {
+		t.Fatalf("client clock not monotonic: %v %v", a, b)
+	}
+	if c := clock.next("d2", now); !c.Equal(now) {
+		t.Fatalf("clock should be per table, got %v", c)
+	}
+
+	if _, err := buildInsertBody(rows, newTSFiller(TimestampRequired, "d1", nil), nil); err == nil {
+		t.Fatalf("expected missing ts error")
+	}
+	if newTSFiller(TimestampServerNow, "d1", nil) != nil {
+		t.Fatalf("server now should use NOW()")
+	}
+}
+
+func TestPoisonRowHelpers(t *testing.T) {
+	items := []chunkItem{
+		{header: "d1 (ts, current) VALUES", group: "(1, 1)", index: 0},
+		{header: "d1 (ts, current) VALUES", group: "(2, 2)", index: 1},
+		{header: "d2 (ts, current) VALUES", group: "(3, 3)", index: 2},
+	}
+	expected := "INSERT INTO d1 (ts, current) VALUES (1, 1) (2, 2) d2 (ts, current) VALUES (3, 3)"
+	if got := renderChunkItems(items); got != expected {
+		t.Fatalf("unexpected sql: %s", got)
+	}
+
+	bad := &taosErrors.TaosError{Code: 0x2603, ErrStr: "Invalid data"}
+	res := BatchResult{Written: 2, Chunks: []ChunkResult{
+		{Indexes: []int{0, 1}, Rows: 2},
+		{Indexes: []int{2}, Rows: 1, Err: bad},
+	}}
+	var be *BatchError
+	if err := res.batchError(); !errors.As(err, &be) || len(be.Rows) != 1 || be.Rows[0].Index != 2 {
+		t.Fatalf("unexpected batch error: %v", err)
+	}
+	if !errors.Is(be, bad) || !isPermanentError(be) || isPermanentError(errors.New("connection refused")) {
+		t.Fatalf("unexpected error classification")
+	}
+	if isPermanentError(&taosErrors.TaosError{Code: 0x000B, ErrStr: "Unable to establish connection"}) {
+		t.Fatalf("connection-class TaosError must be retryable")
+	}
+}
+
+func TestSpoolSegments(t *testing.T) {
+	dir := t.TempDir()
+	s := &Spool{opts: SpoolOptions{Dir: dir, MaxBytes: 1 << 10, SegmentBytes: 40}}
+	stmts := []string{"INSERT INTO d1 (ts, v) VALUES (1, 1)", "INSERT INTO d1 (ts, v) VALUES (2, 2)"}
+	if err := s.appendLocked(stmts); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if len(s.segments) != 2 {
+		t.Fatalf("expected roll to second segment, got %v", s.segments)
+	}
+	if err := s.appendLocked([]string{strings.Repeat("x", 2048)}); err != ErrSpoolFull {
+		t.Fatalf("expected ErrSpoolFull, got %v", err)
+	}
+	s.closeActiveLocked()
+
+	// 模拟进程在写入记录时退出：末尾残留半条记录
+	f, _ := os.OpenFile(s.segmentPath(2), os.O_WRONLY|os.O_APPEND, 0o644)
+	f.Write(encodeSpoolRecord([]byte("INSERT INTO d1 (ts, v) VALUES (3, 3)"))[:10])
+	f.Close()
+
+	r := &Spool{opts: s.opts}
+	if err := r.load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if r.size != s.size || len(r.segments) != 2 {
+		t.Fatalf("unexpected reload: size=%d want %d segments=%v", r.size, s.size, r.segments)
+	}
+	records, _, _ := readSpoolRecords(r.segmentPath(2))
+	if len(records) != 1 || string(records[0]) != stmts[1] {
+		t.Fatalf("unexpected records: %q", records)
+	}
+}
+
+func TestCSVImportHelpers(t *testing.T) {
+	infos := []ColumnInfo{
+		{Name: "ts", Type: "TIMESTAMP"},
+		{Name: "current", Type: "FLOAT"},
+		{Name: "voltage", Type: "INT"},
+		{Name: "location", Type: "NCHAR", Length: 4, IsTag: true},
+	}
+	header := []string{"\ufefftime", "device", "current", "volt", "loc", "note"}
+	spec := ImportSpec{
+		Stable:      "meters",
+		TableColumn: "device",
+		TimeColumn:  "time",
+		Columns:     map[string]string{"volt": "voltage", "loc": "location", "note": ""},
+		Location:    time.UTC,
+	}
+	fields, err := mapCSVHeader(header, infos, spec)
+	if err != nil || !fields[0].isTS || !fields[1].isTbl || !fields[4].col.IsTag || !fields[5].skip {
+		t.Fatalf("unexpected header mapping: %+v err=%v", fields, err)
+	}
+	c := &Client{}
+	wr, err := c.csvWriteRow([]string{"2024-01-01 00:00:00.5", "d1", "1.5", "", "北京", "x"}, header, fields, []string{"location"}, "meters", spec)
+	if err != nil || wr.Table != "d1" || wr.Stable != "meters" || wr.Tags[0] != "北京" {
+		t.Fatalf("unexpected row: %+v err=%v", wr, err)
+	}
+	if ts := wr.Row["ts"].(time.Time); !ts.Equal(time.Date(2024, 1, 1, 0, 0, 0, 5e8, time.UTC)) {
+		t.Fatalf("unexpected ts: %v", ts)
+	}
+	if _, ok := wr.Row["voltage"]; ok {
+		t.Fatalf("empty value should be omitted")
+	}
+	if _, err := c.csvWriteRow([]string{"2024-01-01 00:00:00", "d1", "abc", "1", "x", ""}, header, fields, []string{"location"}, "meters", spec); err == nil {
+		t.Fatalf("expected conversion error")
+	}
+	if _, err := parseColumnValue("北京天津市", infos[3]); err == nil {
+		t.Fatalf("expected NCHAR length error")
+	}
+	if v, _ := parseColumnValue("300", ColumnInfo{Type: "TINYINT"}); v != nil {
+		t.Fatalf("expected TINYINT overflow to fail")
+	}
+	if ts, err := parseCSVTime("1704067200000", "unix_ms", time.UTC); err != nil || ts.Unix() != 1704067200 {
+		t.Fatalf("unexpected unix_ms: %v %v", ts, err)
+	}
+	if _, err := mapCSVHeader([]string{"ts", "unknown"}, infos, ImportSpec{Stable: "meters", TimeColumn: "ts"}); err == nil {
+		t.Fatalf("expected unmatched header error")
+	}
+}
+
+func TestBuildInsertSelectSQL(t *testing.T) {
+	q := SelectQuery{
+		Exprs:    []string{"_wstart", "avg(current)"},
+		From:     "meters",
+		Filter:   Filter{Conditions: []Condition{{Column: "location", Op: "=", Value: "roomA"}}},
+		Interval: time.Minute,
+		Fill:     "NULL",
+	}
+	sqlStr, err := buildInsertSelectSQL("meters_1m", []string{"ts", "current"}, q, nil)
+	expected := "INSERT INTO meters_1m (ts, current) SELECT _wstart, avg(current) FROM meters WHERE location = 'roomA' INTERVAL(60s) FILL(null)"
+	if err != nil || sqlStr != expected {
+		t.Fatalf("unexpected sql: %s err=%v", sqlStr, err)
+	}
+	if _, err := buildInsertSelectSQL("t", []string{"ts"}, q, nil); err == nil {
+		t.Fatalf("expected column count mismatch error")
+	}
+	if sqlStr, _ := buildInsertSelectSQL("t2", nil, SelectQuery{From: "t1"}, nil); sqlStr != "INSERT INTO t2 SELECT * FROM t1" {
+		t.Fatalf("unexpected copy sql: %s", sqlStr)
+	}
+	q.Exprs = []string{"_wstart", "avg(current)", "location"}
+	q.GroupTags = []string{"location"}
+	q.Filter = Filter{}
+	sqlStr, err = buildInsertSelectSQL("loc_1m", nil, q, nil)
+	expected = "INSERT INTO loc_1m SELECT _wstart, avg(current), location FROM meters PARTITION BY location INTERVAL(60s) FILL(null)"
+	if err != nil || sqlStr != expected {
+		t.Fatalf("unexpected partition sql: %s err=%v", sqlStr, err)
+	}
+	sqlStr, _ = buildInsertSelectSQL("loc_total", nil, SelectQuery{Exprs: []string{"location", "count(*)"}, From: "meters", GroupTags: []string{"location"}}, nil)
+	if sqlStr != "INSERT INTO loc_total SELECT location, count(*) FROM meters GROUP BY location" {
+		t.Fatalf("unexpected group sql: %s", sqlStr)
+	}
+}
+
+func TestCoerceRow(t *testing.T) {
+	infos := []ColumnInfo{
+		{Name: "ts", Type: "TIMESTAMP"},
+		{Name: "current", Type: "FLOAT"},
+		{Name: "phase", Type: "TINYINT UNSIGNED"},
+		{Name: "voltage", Type: "INT"},
+		{Name: "name", Type: "NCHAR", Length: 3},
+		{Name: "location", Type: "VARCHAR", Length: 16, IsTag: true},
+	}
+	type level int16
+	row, errs := coerceRow(infos, map[string]interface{}{
+		"ts": time.Now(), "current": 10, "phase": 3.0, "VOLTAGE": level(220), "name": "",
+	})
+	if len(errs) != 0 || row["current"] != float64(10) || row["phase"] != uint64(3) || row["VOLTAGE"] != int64(220) || row["name"] != "" {
+		t.Fatalf("unexpected coercion: %v %v", row, errs)
+	}
+	if v, err := coerceValue("42", infos[3]); err != nil || v != int64(42) {
+		t.Fatalf("expected numeric string to coerce: %v %v", v, err)
+	}
+	_, errs = coerceRow(infos, map[string]interface{}{
+		"current": "abc", "phase": -1, "voltage": int64(1) << 40, "name": "北京天津", "location": "x", "unknown": 1,
+	})
+	if len(errs) != 6 {
+		t.Fatalf("expected 6 field errors, got %v", errs)
+	}
+	if _, err := coerceValue(1.5, infos[3]); err == nil {
+		t.Fatalf("expected fractional value to be rejected for INT")
+	}
+	if _, err := coerceValue(1e39, infos[1]); err == nil {
+		t.Fatalf("expected FLOAT overflow")
+	}
+	if _, errs := coerceRow(infos, map[string]interface{}{"current": "", "voltage": " "}); len(errs) != 2 {
+		t.Fatalf("expected empty strings rejected for numeric columns, got %v", errs)
+	}
+	for _, v := range []interface{}{12.5, 3, "1.25"} {
+		if got, err := coerceValue(v, ColumnInfo{Type: "DECIMAL(10, 2)"}); err != nil || got != v {
+			t.Fatalf("expected DECIMAL value passed through: %v %v", got, err)
+		}
+	}
+}
+
+type testCelsius float32
+
+type testPoint struct{ X, Y int }
+
+func TestFormatValueExtended(t *testing.T) {
+	s := "x"
+	var nilPtr *int
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{`a\b` + "\n\t", `'a\\b\n\t'`},
+		{VarBinary{0x01, 0xab}, `'\x01ab'`},
+		{[]byte("abc"), "'abc'"},
+		{json.RawMessage(`{"k":"it's"}`), `'{"k":"it\'s"}'`},
+		{&s, "'x'"},
+		{nilPtr, "NULL"},
+		{sql.NullFloat64{Float64: 1.5, Valid: true}, "1.5"},
+		{sql.NullString{}, "NULL"},
+		{1500 * time.Millisecond, "1500000000"},
+		{testCelsius(21.5), "21.5"},
+		{float32(3.14), "3.14"},
+	}
+	for _, c := range cases {
+		if got, err := formatValue(c.in); err != nil || got != c.want {
+			t.Fatalf("formatValue(%#v) = %s, %v; want %s", c.in, got, err, c.want)
+		}
+	}
+	if _, err := formatValue("a\x00b"); err == nil {
+		t.Fatalf("expected control character error")
+	}
+	where, err := Filter{Conditions: []Condition{{Column: "name", Op: "like", Value: "a\\_b%'"}}}.buildWhere(nil)
+	if err != nil || where != "WHERE name LIKE 'a\\_b%'''" {
+		t.Fatalf("unexpected LIKE where: %s err=%v", where, err)
+	}
+	if v, err := coerceValue([]byte{0x01}, ColumnInfo{Type: "VARBINARY", Length: 8}); err != nil || v.(VarBinary)[0] != 0x01 {
+		t.Fatalf("expected VarBinary for VARBINARY column, got %#v %v", v, err)
+	}
+	if v, err := coerceValue([]byte("x"), ColumnInfo{Type: "VARCHAR", Length: 8}); err != nil || v != "x" {
+		t.Fatalf("expected string for VARCHAR column, got %#v %v", v, err)
+	}
+	if _, err := formatValue(math.NaN()); err == nil {
+		t.Fatalf("expected NaN error by default")
+	}
+	c, other := &Client{}, &Client{}
+	c.SetNaNPolicy(NaNAsNull)
+	if v, _ := c.codec().formatValue(math.Inf(1)); v != "NULL" {
+		t.Fatalf("expected NULL for Inf, got %s", v)
+	}
+	if _, err := other.codec().formatValue(math.Inf(1)); err == nil {
+		t.Fatalf("NaN policy leaked to another client")
+	}
+
+	c.RegisterValueEncoder(testPoint{}, func(v interface{}) (interface{}, error) {
+		p := v.(testPoint)
+		return fmt.Sprintf("POINT(%d %d)", p.X, p.Y), nil
+	})
+	vc := c.codec()
+	if v, err := vc.formatValue(testPoint{1, 2}); err != nil || v != "'POINT(1 2)'" {
+		t.Fatalf("custom encoder failed: %s %v", v, err)
+	}
+	if _, err := other.codec().formatValue(testPoint{1, 2}); err == nil {
+		t.Fatalf("value encoder leaked to another client")
+	}
+	where, err = Filter{Conditions: []Condition{{Column: "pos", Op: "=", Value: testPoint{3, 4}}}}.buildWhere(vc)
+	if err != nil || where != "WHERE pos = 'POINT(3 4)'" {
+		t.Fatalf("unexpected where with encoder: %s err=%v", where, err)
+	}
+	c.RegisterValueEncoder(testPoint{}, nil)
+	if v, err := vc.formatValue(testPoint{1, 2}); err != nil || v != "'POINT(1 2)'" {
+		t.Fatalf("codec snapshot changed after unregister: %s %v", v, err)
+	}
+	if _, err := c.codec().formatValue(testPoint{1, 2}); err == nil {
+		t.Fatalf("expected error after unregistering encoder")
+	}
+}
+
+func TestPlanNewColumns(t *testing.T) {
+	infos := []ColumnInfo{
+		{Name: "ts", Type: "TIMESTAMP"},
+		{Name: "current", Type: "FLOAT"},
+		{Name: "location", Type: "VARCHAR", IsTag: true},
+	}
+	se := &SchemaEvolution{Allow: []string{"metric_*", "fw"}, Types: map[string]string{"FW": "VARCHAR(16)"}, MaxColumns: 4}
+	rows := []map[string]interface{}{
+		{"ts": time.Now(), "current": 1.0, "metric_a": nil},
+		{"metric_a": int32(5), "fw": "1.2.3"},
+	}
+	plan, err := planNewColumns(infos, rows, se)
+	if err != nil || len(plan) != 2 || plan[0] != (ColumnDef{Name: "fw", Type: "VARCHAR(16)"}) || plan[1] != (ColumnDef{Name: "metric_a", Type: "INT"}) {
+		t.Fatalf("unexpected plan: %+v err=%v", plan, err)
+	}
+	if _, err := planNewColumns(infos, []map[string]interface{}{{"other": 1}}, se); err == nil {
+		t.Fatalf("expected allowlist error")
+	}
+	rows = append(rows, map[string]interface{}{"metric_b": 1.5})
+	if _, err := planNewColumns(infos, rows, se); err == nil {
+		t.Fatalf("expected max column error")
+	}
+	if _, err := planNewColumns(infos, []map[string]interface{}{{"metric_c": nil}}, se); err == nil {
+		t.Fatalf("expected inference error for nil-only column")
+	}
+	if typ := inferColumnType(strings.Repeat("x", 100)); typ != "VARCHAR(128)" {
+		t.Fatalf("unexpected string type: %s", typ)
+	}
+	if !isUnknownColumn(errors.New("[0x2602] Invalid column name: metric_a")) {
+		t.Fatalf("expected unknown column detection")
+	}
+}
+
+func TestUpdateBatch(t *testing.T) {
+	targets := []UpdateTarget{{Table: "d1", TS: 10}, {Table: "d2", TS: 20}, {Table: "d1", TS: 30}}
+	tables := updateBatch(targets, "", map[string]interface{}{"status": "ok"})
+	if len(tables) != 2 || tables[0].Table != "d1" || len(tables[0].Rows) != 2 || tables[1].Table != "d2" {
+		t.Fatalf("unexpected batch: %+v", tables)
+	}
+	if row := tables[0].Rows[1]; row["ts"] != int64(30) || row["status"] != "ok" || len(row) != 2 {
+		t.Fatalf("unexpected row: %+v", row)
+	}
+	tables = updateBatch([]UpdateTarget{{Table: "d1", TS: 10, Key: int32(7)}}, "seq", map[string]interface{}{"status": "ok"})
+	if row := tables[0].Rows[0]; row["seq"] != int32(7) || len(row) != 3 {
+		t.Fatalf("unexpected composite key row: %+v", row)
+	}
+	c := &Client{}
+	if _, err := c.UpdateWhere("meters", map[string]interface{}{"status": "ok"}, Filter{}, UpdateOptions{DryRun: true}); err == nil {
+		t.Fatalf("expected error for update without WHERE")
+	}
+	if _, err := c.UpdateWhere("meters", map[string]interface{}{"TS": 1}, Filter{}, UpdateOptions{}); err == nil {
+		t.Fatalf("expected error for updating ts")
+	}
+	if err := c.Upsert("d1", nil, map[string]interface{}{"status": "ok"}); err == nil {
+		t.Fatalf("expected error for nil ts")
+	}
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	cols := []ColumnDef{{Name: "current", Type: "FLOAT"}, {Name: "seq", Type: "INT", PrimaryKey: true}}
+	sqlStr, err := buildCreateStableSQL("meters", cols, []ColumnDef{{Name: "location", Type: "NCHAR(64)"}})
+	if err != nil || sqlStr != "CREATE STABLE IF NOT EXISTS meters (ts TIMESTAMP, seq INT PRIMARY KEY, current FLOAT) TAGS (location NCHAR(64))" {
+		t.Fatalf("unexpected create stable: %s err=%v", sqlStr, err)
+	}
+	if _, err := buildCreateStableSQL("meters", []ColumnDef{{Name: "v", Type: "FLOAT", PrimaryKey: true}}, nil); err == nil {
+		t.Fatalf("expected error for FLOAT primary key")
+	}
+	if _, err := buildCreateStableSQL("meters", append(cols, ColumnDef{Name: "k2", Type: "BIGINT", PrimaryKey: true}), nil); err == nil {
+		t.Fatalf("expected error for two primary keys")
+	}
+	if !isPrimaryKeyType("varchar(32)") || !isPrimaryKeyType("BIGINT  UNSIGNED") || isPrimaryKeyType("TIMESTAMP") {
+		t.Fatalf("unexpected primary key type check")
+	}
+	infos := []ColumnInfo{{Name: "ts", Type: "TIMESTAMP"}, {Name: "seq", Type: "INT", PrimaryKey: true}, {Name: "current", Type: "FLOAT"}}
+	if _, fes := coerceRow(infos, map[string]interface{}{"ts": time.Now(), "current": 1.5}); len(fes) != 1 || fes[0].Column != "seq" {
+		t.Fatalf("expected missing primary key error, got %+v", fes)
+	}
+	if _, fes := coerceRow(infos, map[string]interface{}{"ts": time.Now(), "SEQ": 3}); len(fes) != 0 {
+		t.Fatalf("unexpected errors: %+v", fes)
+	}
+	c := &Client{primaryKeys: map[string]string{"meters": "seq"}}
+	f := Filter{Conditions: []Condition{{Column: "ts", Op: "=", Value: 1}, {Column: "seq", Op: "=", Value: 2}}}
+	if err := c.checkDeleteKey("meters", f); err == nil {
+		t.Fatalf("expected error when deleting by composite key")
+	}
+	if err := c.checkDeleteKey("meters", Filter{Conditions: f.Conditions[:1]}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestKeyOwnerCached(t *testing.T) {
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		if strings.Contains(q, "ins_tables") {
+			return []string{"stable_name"}, [][]driver.Value{{"meters"}}, nil
+		}
+		return nil, nil, nil
+	})
+	c.primaryKeys = map[string]string{}
+	owners := func() (n int) {
+		for _, q := range fdb.executed() {
+			if strings.Contains(q, "ins_tables") {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 0; i < 3; i++ {
+		if owner, err := c.keyOwner("d1001"); err != nil || owner != "meters" {
+			t.Fatalf("unexpected owner %q: %v", owner, err)
+		}
+	}
+	if n := owners(); n != 1 {
+		t.Fatalf("expected a single stable lookup, got %d", n)
+	}
+	c.InvalidateSchema("meters")
+	if _, err := c.keyOwner("d1001"); err != nil {
+		t.Fatal(err)
+	}
+	if n := owners(); n != 2 {
+		t.Fatalf("expected lookup after invalidation, got %d", n)
+	}
+}
+
+func TestSubTableCacheAppliedTags(t *testing.T) {
+	c, fdb := newFakeClient(t, nil)
+	c.EnableSubTableCache(SubTableCacheOptions{})
+	creates := func() (n int) {
+		for _, q := range fdb.executed() {
+			if strings.HasPrefix(q, "CREATE TABLE") {
+				n++
+			}
+		}
+		return n
+	}
+	if err := c.EnsureSubTable("d1", "meters", []interface{}{"bj"}); err != nil {
+		t.Fatal(err)
+	}
+	// 已有子表传入不同 TAGS：建表语句不生效，缓存仍为原 TAGS
+	if err := c.EnsureSubTable("d1", "meters", []interface{}{"sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EnsureSubTable("d1", "meters", []interface{}{"bj"}); err != nil || creates() != 2 {
+		t.Fatalf("expected original tags cached, creates=%d err=%v", creates(), err)
+	}
+	if err := c.EnsureSubTable("d1", "meters", []interface{}{"sh"}); err != nil || creates() != 3 {
+		t.Fatalf("expected miss for unapplied tags, creates=%d err=%v", creates(), err)
+	}
+	if err := c.UseDatabase("other"); err != nil {
+		t.Fatal(err)
+	}
+	if st := c.SubTableCacheStats(); st.Size != 0 {
+		t.Fatalf("expected cache cleared on UseDatabase, size=%d", st.Size)
+	}
+}
+
+func TestInsertSkipsCachedTags(t *testing.T) {
+	var mu sync.Mutex
+	dropped := false
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if dropped && strings.HasPrefix(q, "INSERT") && !strings.Contains(q, "USING") {
+			return nil, nil, errors.New("[0x2603] Table does not exist")
+		}
+		return nil, nil, nil
+	})
+	c.EnableSubTableCache(SubTableCacheOptions{})
+	insert := func() string {
+		t.Helper()
+		if err := c.InsertAuto("d1", "meters", []interface{}{"bj"}, map[string]interface{}{"ts": int64(1), "v": 1}); err != nil {
+			t.Fatalf("insert auto: %v", err)
+		}
+		q := fdb.executed()
+		return q[len(q)-1]
+	}
+	if q := insert(); !strings.Contains(q, "USING meters TAGS ('bj')") {
+		t.Fatalf("expected create clause on first write: %s", q)
+	}
+	if q := insert(); q != "INSERT INTO d1 (ts, v) VALUES (1, 1)" {
+		t.Fatalf("expected create clause skipped on cache hit: %s", q)
+	}
+	// 子表在别处被删除：省略建表子句的语句失败后带上 USING ... TAGS 重试
+	mu.Lock()
+	dropped = true
+	mu.Unlock()
+	if q := insert(); !strings.Contains(q, "USING meters TAGS ('bj')") {
+		t.Fatalf("expected retry with create clause: %s", q)
+	}
+}
+
+func TestPrimaryKeyCacheByStable(t *testing.T) {
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		switch {
+		case strings.HasPrefix(q, "SELECT stable_name"):
+			return []string{"stable_name"}, [][]driver.Value{{"meters"}}, nil
+		case strings.HasPrefix(q, "DESCRIBE"):
+			return []string{"field", "type", "length", "note"}, [][]driver.Value{
+				{"ts", "TIMESTAMP", int64(8), ""},
+				{"seq", "INT", int64(4), "COMPOSITE KEY"},
+				{"location", "VARCHAR", int64(16), "TAG"},
+			}, nil
+		}
+		return nil, nil, nil
+	})
+	describes := func() (n int) {
+		for _, q := range fdb.executed() {
+			if strings.HasPrefix(q, "DESCRIBE") {
+				if q != "DESCRIBE meters" {
+					t.Fatalf("unexpected describe: %s", q)
+				}
+				n++
+			}
+		}
+		return n
+	}
+	for _, sub := range []string{"d1", "d2", "d3"} {
+		if err := c.Upsert(sub, int64(1), map[string]interface{}{"seq": 1, "v": 2}); err != nil {
+			t.Fatalf("upsert %s: %v", sub, err)
+		}
+	}
+	if err := c.Upsert("d4", int64(1), map[string]interface{}{"v": 2}); err == nil {
+		t.Fatalf("expected missing primary key error")
+	}
+	if err := c.checkDeleteKey("d5", Filter{Conditions: []Condition{{Column: "location", Op: "=", Value: "bj"}}}); err != nil {
+		t.Fatalf("unexpected error for tag filter: %v", err)
+	}
+	if n := describes(); n != 1 {
+		t.Fatalf("expected one DESCRIBE per stable, got %d", n)
+	}
+	if err := c.DropStable("meters", true); err != nil {
+		t.Fatalf("drop stable: %v", err)
+	}
+	if _, err := c.primaryKeyOf("d1"); err != nil || describes() != 2 {
+		t.Fatalf("expected cache cleared by DropStable, describes=%d err=%v", describes(), err)
+	}
+	if err := c.DropTable("meters", true); err != nil {
+		t.Fatalf("drop table: %v", err)
+	}
+	if _, err := c.primaryKeyOf("d1"); err != nil || describes() != 3 {
+		t.Fatalf("expected cache cleared by DropTable, describes=%d err=%v", describes(), err)
+	}
+}
+
+func TestColumnCompression(t *testing.T) {
+	cols := []ColumnDef{
+		{Name: "ts", Encode: "delta-i", Level: "High"},
+		{Name: "current", Type: "FLOAT", Encode: "delta-d", Compress: "zstd"},
+	}
+	sqlStr, err := buildCreateStableSQL("meters", cols, []ColumnDef{{Name: "location", Type: "NCHAR(64)"}})
+	if err != nil || sqlStr != "CREATE STABLE IF NOT EXISTS meters (ts TIMESTAMP ENCODE 'delta-i' LEVEL 'high', current FLOAT ENCODE 'delta-d' COMPRESS 'zstd') TAGS (location NCHAR(64))" {
+		t.Fatalf("unexpected create stable: %s err=%v", sqlStr, err)
+	}
+	if _, err := buildCreateStableSQL("meters", []ColumnDef{{Name: "v", Type: "FLOAT", Compress: "gzip"}}, nil); err == nil {
+		t.Fatalf("expected error for unsupported compress")
+	}
+	if _, err := buildCreateStableSQL("meters", cols, []ColumnDef{{Name: "g", Type: "INT", Level: "low"}}); err == nil {
+		t.Fatalf("expected error for tag compression")
+	}
+	def := infoColumnDef(ColumnInfo{Name: "current", Type: "FLOAT", Length: 4, Encode: "delta-d", Compress: "lz4", Level: "medium"})
+	if def != (ColumnDef{Name: "current", Type: "FLOAT", Encode: "delta-d", Compress: "lz4", Level: "medium"}) {
+		t.Fatalf("unexpected column def: %+v", def)
+	}
+	if def := infoColumnDef(ColumnInfo{Name: "v", Type: "DOUBLE", Encode: "future", Compress: "lz4"}); def.Encode != "" || def.Compress != "" {
+		t.Fatalf("expected unknown options to be dropped: %+v", def)
+	}
+}
+
+func TestTagIndexName(t *testing.T) {
+	if name := tagIndexName("Meters", "groupId"); name != "idx_meters_groupid" {
+		t.Fatalf("unexpected index name: %s", name)
+	}
+	long := tagIndexName(strings.Repeat("s", 60), "tag")
+	if len(long) > maxIndexNameLen || !strings.HasPrefix(long, "idx_") || long != tagIndexName(strings.Repeat("S", 60), "TAG") {
+		t.Fatalf("unexpected long index name: %s", long)
+	}
+	c := &Client{}
+	if _, err := c.CreateTagIndex("meters", "bad-tag"); err == nil {
+		t.Fatalf("expected error for invalid tag")
+	}
+	if err := c.DropIndex("idx;drop", true); err == nil {
+		t.Fatalf("expected error for invalid index name")
+	}
+}
+
+func TestDropIndexQualified(t *testing.T) {
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		return nil, nil, nil
+	})
+	if err := c.DropIndex("power.idx_meters_groupid", true); err != nil {
+		t.Fatalf("drop index: %v", err)
+	}
+	if err := c.DropIndex("idx_meters_groupid", false); err != nil {
+		t.Fatalf("drop index: %v", err)
+	}
+	if err := c.DropIndex("power.idx;drop", true); err == nil {
+		t.Fatalf("expected error for invalid qualified index name")
+	}
+	got := fdb.executed()
+	if len(got) != 2 || got[0] != "DROP INDEX IF EXISTS power.idx_meters_groupid" || got[1] != "DROP INDEX idx_meters_groupid" {
+		t.Fatalf("unexpected statements: %v", got)
+	}
+}
+
+func TestExportSchemaIndex(t *testing.T) {
+	c, _ := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		if strings.Contains(q, "ins_indexes") {
+			return []string{"index_name", "index_type", "table_name", "column_name"}, [][]driver.Value{
+				{"idx_meters_groupid", "tag", "meters", "groupid"},
+				{"sma_idx", "sma", "meters", "current"},
+			}, nil
+		}
+		return []string{"name"}, nil, nil
+	})
+	var buf strings.Builder
+	if err := c.ExportSchema("power", &buf); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if !strings.Contains(buf.String(), "CREATE INDEX IF NOT EXISTS power.idx_meters_groupid ON power.meters (groupid);") || strings.Contains(buf.String(), "sma_idx") {
+		t.Fatalf("unexpected index export:\n%s", buf.String())
+	}
+}
+
+func TestExportSchemaTagError(t *testing.T) {
+	c, _ := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		return []string{"name"}, nil, nil
+	})
+	var buf strings.Builder
+	if err := c.ExportSchema("power", &buf); err != nil {
+		t.Fatalf("export schema: %v", err)
+	}
+	if !strings.Contains(buf.String(), "USE power;\n") {
+		t.Fatalf("expected USE statement:\n%s", buf.String())
+	}
+	if _, err := tagLiteral("NCHAR(16)", "bad\x01"); err == nil {
+		t.Fatalf("expected error for control character in tag value")
+	}
+	if lit, err := tagLiteral("VARCHAR(16)", "it's"); err != nil || lit != "'it''s'" {
+		t.Fatalf("unexpected literal %q: %v", lit, err)
+	}
+}
+
+func TestCreateTagIndexDatabase(t *testing.T) {
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(q, "SELECT") {
+			return []string{"index_name", "db_name", "table_name", "column_name"}, nil, nil
+		}
+		return nil, nil, nil
+	})
+	if _, err := c.CreateTagIndex("meters", "groupId"); err == nil {
+		t.Fatalf("expected error without database")
+	}
+	if len(fdb.executed()) != 0 {
+		t.Fatalf("unexpected queries: %v", fdb.executed())
+	}
+	name, err := c.CreateTagIndex("power.meters", "groupId")
+	if err != nil || name != "idx_meters_groupid" {
+		t.Fatalf("create tag index: %s %v", name, err)
+	}
+	want := []string{
+		"SELECT * FROM information_schema.ins_indexes WHERE table_name = 'meters' AND db_name = 'power'",
+		"CREATE INDEX power.idx_meters_groupid ON power.meters (groupId)",
+	}
+	if got := fdb.executed(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected statements: %q", got)
+	}
+}
+
+func TestListTSMAsQualified(t *testing.T) {
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		return []string{"tsma_name", "db_name", "table_name", "interval", "func_list"}, [][]driver.Value{
+			{"tsma1", "power", "meters", "1m", "avg(current)"},
+		}, nil
+	})
+	tsmas, err := c.ListTSMAs("power.meters")
+	if err != nil || len(tsmas) != 1 || tsmas[0].DB != "power" {
+		t.Fatalf("unexpected tsmas %+v: %v", tsmas, err)
+	}
+	if _, err := c.ListTSMAs("power.bad-name"); err == nil {
+		t.Fatalf("expected error for invalid stable name")
+	}
+	got := fdb.executed()
+	if len(got) != 1 || got[0] != "SELECT * FROM information_schema.ins_tsmas WHERE table_name = 'meters' AND db_name = 'power'" {
+		t.Fatalf("unexpected queries: %v", got)
+	}
+}
+
+func TestBuildCreateTSMASQL(t *testing.T) {
+	sqlStr, err := buildCreateTSMASQL("meters_5m", "meters", []string{"AVG(current)", " max( voltage ) ", "count(*)"}, 5*time.Minute)
+	if err != nil || sqlStr != "CREATE TSMA meters_5m ON meters FUNCTION(avg(current), max(voltage), count(*)) INTERVAL(5m)" {
+		t.Fatalf("unexpected create tsma: %s err=%v", sqlStr, err)
+	}
+	if iv, _ := tsmaInterval(2 * time.Hour); iv != "2h" {
+		t.Fatalf("unexpected interval: %s", iv)
+	}
+	if iv, _ := tsmaInterval(48 * time.Hour); iv != "2d" {
+		t.Fatalf("unexpected interval: %s", iv)
+	}
+	bad := []struct {
+		funcs    []string
+		interval time.Duration
+	}{
+		{[]string{"median(current)"}, time.Minute},
+		{[]string{"avg(current); drop"}, time.Minute},
+		{[]string{"sum(*)"}, time.Minute},
+		{nil, time.Minute},
+		{[]string{"avg(current)"}, 30 * time.Second},
+		{[]string{"avg(current)"}, 90 * time.Second},
+	}
+	for _, b := range bad {
+		if _, err := buildCreateTSMASQL("t", "meters", b.funcs, b.interval); err == nil {
+			t.Fatalf("expected error for %v %s", b.funcs, b.interval)
+		}
+	}
+}
+
+// fakeDB 测试用的内存驱动：记录执行的语句，由 handle 决定每条语句的结果
+type fakeDB struct {
+	mu     sync.Mutex
+	stmts  []string
+	handle func(query string) ([]string, [][]driver.Value, error)
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = map[string]*fakeDB{}
+)
+
+func init() {
+	sql.Register("tdormfake", fakeDriver{})
+}
+
+// newFakeClient 返回使用 fakeDB 的 Client
+func newFakeClient(t *testing.T, handle func(query string) ([]string, [][]driver.Value, error)) (*Client, *fakeDB) {
+	t.Helper()
+	fdb := &fakeDB{handle: handle}
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = fdb
+	fakeDBsMu.Unlock()
+	db, err := sql.Open("tdormfake", t.Name())
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Client{DB: db}, fdb
+}
+
+func (f *fakeDB) run(query string) ([]string, [][]driver.Value, error) {
+	f.mu.Lock()
+	f.stmts = append(f.stmts, query)
+	h := f.handle
+	f.mu.Unlock()
+	if h == nil {
+		return nil, nil, nil
+	}
+	return h(query)
+}
+
+func (f *fakeDB) executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.stmts...)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("tx not supported") }
+
+func (c *fakeConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	_, rows, err := c.db.run(query)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+func (c *fakeConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	cols, rows, err := c.db.run(query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{cols: cols, rows: rows}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+	pos  int
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+func TestSpoolDropsAfterMaxAttempts(t *testing.T) {
+	var mu sync.Mutex
+	code := int32(0)
+	c, _ := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if code != 0 && strings.Contains(q, "(1, ") {
+			return nil, nil, &taosErrors.TaosError{Code: code, ErrStr: "rejected"}
+		}
+		if code != 0 && !strings.Contains(q, "(1, ") {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.New("connection refused")
+	})
+	var dropped []string
+	s, err := c.OpenSpool(SpoolOptions{Dir: t.TempDir(), RetryInterval: time.Hour, MaxAttempts: 3, OnDrop: func(err error, stmt string) {
+		dropped = append(dropped, stmt)
+	}})
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	defer s.Close()
+	for i := 1; i <= 2; i++ {
+		if err := s.Insert("d1", map[string]interface{}{"ts": int64(i), "v": i}); err != nil {
+			t.Fatalf("spool insert: %v", err)
+		}
+	}
+	// 连接错误不计数
+	for i := 0; i < 5; i++ {
+		if err := s.Flush(); err == nil || len(dropped) != 0 {
+			t.Fatalf("expected data kept while unreachable: err=%v dropped=%v", err, dropped)
+		}
+	}
+	// 不在允许清单中的服务端错误码：重试 MaxAttempts 次后丢弃，后续记录继续重放
+	mu.Lock()
+	code = 0x0914
+	mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if err := s.Flush(); err == nil || len(dropped) != 0 {
+			t.Fatalf("attempt %d: expected retry, err=%v dropped=%v", i, err, dropped)
+		}
+	}
+	if err := s.Flush(); err != nil || len(dropped) != 1 || s.Pending() != 0 {
+		t.Fatalf("expected poison record dropped: err=%v dropped=%v pending=%d", err, dropped, s.Pending())
+	}
+	// 解析器范围内未列出的错误码直接视为永久错误
+	if !isPermanentError(&taosErrors.TaosError{Code: 0x2650, ErrStr: "Invalid"}) || isPermanentError(&taosErrors.TaosError{Code: 0x0914}) {
+		t.Fatalf("unexpected permanent classification")
+	}
+}
+
+func TestSpoolKeepsRetryableTaosErrors(t *testing.T) {
+	var mu sync.Mutex
+	down := true
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return nil, nil, &taosErrors.TaosError{Code: 0x000B, ErrStr: "Unable to establish connection"}
+		}
+		return nil, nil, nil
+	})
+	var dropped []string
+	s, err := c.OpenSpool(SpoolOptions{Dir: t.TempDir(), RetryInterval: time.Hour, OnDrop: func(err error, stmt string) {
+		dropped = append(dropped, stmt)
+	}})
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	defer s.Close()
+	if err := s.Insert("d1", map[string]interface{}{"ts": int64(1), "v": 1}); err != nil {
+		t.Fatalf("expected statement to be spooled, got %v", err)
+	}
+	if s.Pending() == 0 {
+		t.Fatalf("expected pending data")
+	}
+	if err := s.Flush(); err == nil || s.Pending() == 0 || len(dropped) != 0 {
+		t.Fatalf("expected data kept during outage: err=%v pending=%d dropped=%v", err, s.Pending(), dropped)
+	}
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if err := s.Flush(); err != nil || s.Pending() != 0 || len(dropped) != 0 {
+		t.Fatalf("expected replay after recovery: err=%v pending=%d dropped=%v", err, s.Pending(), dropped)
+	}
+	last := fdb.executed()
+	if got := last[len(last)-1]; !strings.Contains(got, "INSERT INTO d1") {
+		t.Fatalf("unexpected replayed statement: %s", got)
+	}
+}
+
+func TestBisectOnlyRowDataErrors(t *testing.T) {
+	var mu sync.Mutex
+	fail := func(q string) error { return nil }
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(q, "INSERT") {
+			return nil, nil, fail(q)
+		}
+		return nil, nil, nil
+	})
+	c.SetBatchOptions(BatchOptions{IsolateFailures: true})
+	rows := []map[string]interface{}{
+		{"ts": int64(1), "v": "a"}, {"ts": int64(2), "v": "bad"}, {"ts": int64(3), "v": "c"}, {"ts": int64(4), "v": "d"},
+	}
+	countInserts := func() int {
+		n := 0
+		for _, q := range fdb.executed() {
+			if strings.HasPrefix(q, "INSERT") {
+				n++
+			}
+		}
+		return n
+	}
+
+	// 某一行的值非法：二分定位坏行，其余行写入
+	fail = func(q string) error {
+		if strings.Contains(q, "'bad'") {
+			return &taosErrors.TaosError{Code: 0x2605, ErrStr: "Invalid value type"}
+		}
+		return nil
+	}
+	res, err := c.BatchInsertTablesWithResult([]TableRows{{Table: "d1", Rows: rows}})
+	var be *BatchError
+	if !errors.As(err, &be) || len(be.Rows) != 1 || be.Rows[0].Index != 1 || res.Written != 3 {
+		t.Fatalf("expected single bad row, got %v written=%d", err, res.Written)
+	}
+
+	// 整条语句级的错误（库不存在）：不拆分
+	before := countInserts()
+	fail = func(q string) error { return &taosErrors.TaosError{Code: 0x0388, ErrStr: "Database not exist"} }
+	res, _ = c.BatchInsertTablesWithResult([]TableRows{{Table: "d1", Rows: rows}})
+	if n := countInserts() - before; n != 1 || res.Failed != 4 {
+		t.Fatalf("expected no bisection, got %d statements failed=%d", n, res.Failed)
+	}
+
+	// 两个坏行分别位于两半：继续拆分，其余 6 行写入
+	rows = nil
+	for i := 0; i < 8; i++ {
+		v := fmt.Sprintf("v%d", i)
+		if i == 1 || i == 6 {
+			v = "bad"
+		}
+		rows = append(rows, map[string]interface{}{"ts": int64(i + 1), "v": v})
+	}
+	fail = func(q string) error {
+		if strings.Contains(q, "'bad'") {
+			return &taosErrors.TaosError{Code: 0x2653, ErrStr: "Value too long"}
+		}
+		return nil
+	}
+	res, err = c.BatchInsertTablesWithResult([]TableRows{{Table: "d1", Rows: rows}})
+	if !errors.As(err, &be) || len(be.Rows) != 2 || be.Rows[0].Index != 1 || be.Rows[1].Index != 6 || res.Written != 6 || res.Failed != 2 {
+		t.Fatalf("expected two bad rows isolated, got %v written=%d failed=%d", err, res.Written, res.Failed)
+	}
+}
+
+// fakeStmt 记录加入批次的子表，Exec 时清空
+type fakeStmt struct {
+	failTable string
+	current   string
+	queued    []string
+	executed  []string
+	closed    bool
+}
+
+func (s *fakeStmt) SetTableName(name string) error {
+	if name == s.failTable {
+		return errors.New("set table name failed")
+	}
+	s.current = name
+	return nil
+}
+func (s *fakeStmt) SetTags(*param.Param, *param.ColumnType) error     { return nil }
+func (s *fakeStmt) BindParam([]*param.Param, *param.ColumnType) error { return nil }
+func (s *fakeStmt) AddBatch() error {
+	s.queued = append(s.queued, s.current)
+	return nil
+}
+func (s *fakeStmt) Exec() error {
+	s.executed, s.queued = s.queued, nil
+	return nil
+}
+func (s *fakeStmt) GetAffectedRows() int { return len(s.executed) }
+func (s *fakeStmt) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestPreparedInserterResetOnError(t *testing.T) {
+	var stmts []*fakeStmt
+	p := &PreparedInserter{
+		c:    &Client{},
+		cols: []ColumnInfo{{Name: "ts", Type: "TIMESTAMP"}, {Name: "v", Type: "INT"}},
+		tags: []ColumnInfo{{Name: "loc", Type: "VARCHAR", Length: 16}},
+		prepare: func() (preparedStmt, error) {
+			s := &fakeStmt{failTable: "d2"}
+			stmts = append(stmts, s)
+			return s, nil
+		},
+	}
+	if err := p.reset(); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	row := []map[string]interface{}{{"ts": time.Now(), "v": 1}}
+	// 校验失败：不加入任何批次
+	if _, err := p.InsertTables([]TableRows{{Table: "d1", Tags: []interface{}{"a"}, Rows: row}, {Table: "d3", Rows: row}}); err == nil {
+		t.Fatalf("expected tag count error")
+	}
+	if len(stmts[0].queued) != 0 {
+		t.Fatalf("expected nothing queued, got %v", stmts[0].queued)
+	}
+	// 加入批次中途失败：丢弃 stmt 并重新 prepare
+	if _, err := p.InsertTables([]TableRows{{Table: "d1", Tags: []interface{}{"a"}, Rows: row}, {Table: "d2", Tags: []interface{}{"b"}, Rows: row}}); err == nil {
+		t.Fatalf("expected set table name error")
+	}
+	if len(stmts) != 2 || !stmts[0].closed {
+		t.Fatalf("expected stmt to be re-prepared")
+	}
+	if n, err := p.InsertTables([]TableRows{{Table: "d4", Tags: []interface{}{"c"}, Rows: row}}); err != nil || n != 1 || len(stmts[1].executed) != 1 || stmts[1].executed[0] != "d4" {
+		t.Fatalf("expected only d4 executed, got %v n=%d err=%v", stmts[1].executed, n, err)
+	}
+}
+
+func TestBindColumnsTimestampsAndRanges(t *testing.T) {
+	c := &Client{}
+	c.SetTimestampPolicy(TimestampServerOffset)
+	p := &PreparedInserter{c: c}
+	cols := []ColumnInfo{{Name: "ts", Type: "TIMESTAMP"}, {Name: "v", Type: "TINYINT"}}
+	rows := []map[string]interface{}{{"v": 1}, {"v": 2}, {"v": 3}}
+	fill := p.tsFiller("d1")
+	var got []time.Time
+	for i := range rows {
+		ts, err := fill(i)
+		if err != nil {
+			t.Fatalf("fill ts: %v", err)
+		}
+		got = append(got, ts)
+	}
+	for i := 1; i < len(got); i++ {
+		if !got[i].After(got[i-1]) {
+			t.Fatalf("expected increasing timestamps, got %v", got)
+		}
+	}
+	if _, _, err := bindColumns(cols, rows, 0, p.tsFiller("d1")); err != nil {
+		t.Fatalf("bind columns: %v", err)
+	}
+	c.SetTimestampPolicy(TimestampRequired)
+	if _, _, err := bindColumns(cols, rows, 0, p.tsFiller("d1")); err == nil {
+		t.Fatalf("expected missing ts error")
+	}
+	if _, _, err := bindColumns(cols, []map[string]interface{}{{"ts": time.Now(), "v": 300}}, 0, p.tsFiller("d1")); err == nil {
+		t.Fatalf("expected TINYINT overflow error")
+	}
+	if _, err := bindUint(int64(-1), "INT UNSIGNED"); err == nil {
+		t.Fatalf("expected negative unsigned error")
+	}
+	if _, err := bindInt(uint64(math.MaxUint64), "BIGINT"); err == nil {
+		t.Fatalf("expected BIGINT overflow error")
+	}
+	if n, err := bindUint(uint16(65535), "SMALLINT UNSIGNED"); err != nil || n != 65535 {
+		t.Fatalf("unexpected unsigned bind: %d %v", n, err)
+	}
+}
+
+func TestSpoolWriteDoesNotHoldLockDuringExec(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	c, _ := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(q, "INSERT") {
+			close(entered)
+			<-release
+		}
+		return nil, nil, nil
+	})
+	s, err := c.OpenSpool(SpoolOptions{Dir: t.TempDir(), RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	defer s.Close()
+	done := make(chan error, 1)
+	go func() { done <- s.Insert("d1", map[string]interface{}{"ts": int64(1), "v": 1}) }()
+	<-entered
+	pending := make(chan int64, 1)
+	go func() { pending <- s.Pending() }()
+	select {
+	case <-pending:
+	case <-time.After(time.Second):
+		t.Fatalf("Pending blocked while Write was executing")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func TestUpdateWherePaging(t *testing.T) {
+	type rec struct {
+		table string
+		ts    int64
+	}
+	data := []rec{{"d1", 1}, {"d2", 1}, {"d1", 2}, {"d2", 2}, {"d1", 3}}
+	gt := regexp.MustCompile(`ts > (\d+)`)
+	eq := regexp.MustCompile(`ts = (\d+)`)
+	lim := regexp.MustCompile(`LIMIT (\d+)`)
+	c, fdb := newFakeClient(t, func(q string) ([]string, [][]driver.Value, error) {
+		switch {
+		case strings.HasPrefix(q, "DESCRIBE"):
+			return []string{"field", "type", "length", "note"}, [][]driver.Value{
+				{"ts", "TIMESTAMP", int64(8), ""},
+				{"status", "VARCHAR", int64(16), ""},
+				{"location", "VARCHAR", int64(16), "TAG"},
+			}, nil
+		case strings.HasPrefix(q, "SELECT tbname"):
+			var out [][]driver.Value
+			for _, r := range data {
+				if m := gt.FindStringSubmatch(q); m != nil && fmt.Sprint(r.ts) <= m[1] {
+					continue
+				}
+				if m := eq.FindStringSubmatch(q); m != nil && fmt.Sprint(r.ts) != m[1] {
+					continue
+				}
+				out = append(out, []driver.Value{r.table, r.ts})
+			}
+			if m := lim.FindStringSubmatch(q); m != nil {
+				var n int
+				fmt.Sscan(m[1], &n)
+				if len(out) > n {
+					out = out[:n]
+				}
+			}
+			return []string{"tbname", "ts"}, out, nil
+		}
+		return nil, nil, nil
+	})
+	f := Filter{Conditions: []Condition{{Column: "status", Op: "=", Value: "old"}}}
+	set := map[string]interface{}{"status": "new"}
+	inserts := func() []string {
+		var out []string
+		for _, q := range fdb.executed() {
+			if strings.HasPrefix(q, "INSERT") {
+				out = append(out, q)
+			}
+		}
+		return out
+	}
+
+	report, err := c.UpdateWhere("meters", set, f, UpdateOptions{DryRun: true, BatchSize: 3})
+	if err != nil || report.Matched != 5 || len(report.Rows) != 5 || report.Written != 0 {
+		t.Fatalf("unexpected dry run report: %+v err=%v", report, err)
+	}
+	seen := map[rec]bool{}
+	for _, r := range report.Rows {
+		seen[rec{r.Table, r.TS}] = true
+	}
+	if len(seen) != 5 || len(inserts()) != 0 {
+		t.Fatalf("dry run should list each row once without writing: %v inserts=%v", report.Rows, inserts())
+	}
+
+	report, err = c.UpdateWhere("meters", set, f, UpdateOptions{BatchSize: 3})
+	if err != nil || report.Written != 5 || len(report.Rows) != 0 || len(inserts()) != 2 {
+		t.Fatalf("unexpected update report: %+v err=%v inserts=%v", report, err, inserts())
+	}
+
+	report, err = c.UpdateWhere("meters", set, Filter{Conditions: f.Conditions, Limit: 2}, UpdateOptions{DryRun: true, BatchSize: 3})
+	if err != nil || report.Matched != 2 {
+		t.Fatalf("expected limit to cap matches: %+v err=%v", report, err)
+	}
+
+	_ = c.EnableSchemaEvolution("meters", SchemaEvolution{})
+	before := len(fdb.executed())
+	if _, err := c.UpdateWhere("meters", map[string]interface{}{"stauts": "new"}, f, UpdateOptions{}); err == nil {
+		t.Fatalf("expected unknown column error")
+	}
+	for _, q := range fdb.executed()[before:] {
+		if strings.HasPrefix(q, "ALTER") || strings.HasPrefix(q, "SELECT tbname") {
+			t.Fatalf("unexpected statement for misspelled column: %s", q)
+		}
+	}
+	if _, err := c.UpdateWhere("meters", map[string]interface{}{"location": "x"}, f, UpdateOptions{}); err == nil {
+		t.Fatalf("expected tag column error")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 未提供 ts 的行的时间戳分配策略
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimestampPolicy 未提供 ts 的行如何分配时间戳
+type TimestampPolicy int
+
+const (
+	// TimestampServerNow 使用服务端 NOW()（默认）；同一语句中的多行时间戳相同，会相互覆盖
+	TimestampServerNow TimestampPolicy = iota
+	// TimestampServerOffset 使用服务端 NOW() 加按行序号的毫秒偏移：第 n 行为 NOW() + na，与输入顺序一致
+	TimestampServerOffset
+	// TimestampClientClock 使用客户端时钟，同一表的时间戳至少递增 1ms，跨调用也不会重复
+	TimestampClientClock
+	// TimestampRequired 缺少 ts 时返回错误
+	TimestampRequired
+)
+
+// tsFiller 为第 row 行（缺少 ts）生成时间戳的 SQL 字面量
+type tsFiller func(row int) (string, error)
+
+// tsClock 按表记录已分配的最近时间戳，保证客户端时钟策略单调递增
+type tsClock struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// next 返回表 table 的下一个时间戳（毫秒精度）
+func (k *tsClock) next(table string, now time.Time) time.Time {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.last == nil {
+		k.last = make(map[string]time.Time)
+	}
+	key := strings.ToLower(table)
+	t := now.Truncate(time.Millisecond)
+	if last, ok := k.last[key]; ok && !t.After(last) {
+		t = last.Add(time.Millisecond)
+	}
+	k.last[key] = t
+	return t
+}
+
+// SetTimestampPolicy 设置未提供 ts 的行的时间戳分配策略，作用于 Insert、BatchInsert 及多表写入
+func (c *Client) SetTimestampPolicy(p TimestampPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tsPolicy = p
+}
+
+// tsFillerFor 为一次写入中的某个表创建时间戳生成器；返回 nil 表示使用 NOW()
+func (c *Client) tsFillerFor(table string) tsFiller {
+	c.mu.RLock()
+	p := c.tsPolicy
+	c.mu.RUnlock()
+	return newTSFiller(p, table, &c.tsClock)
+}
+
+// newTSFiller 按策略创建时间戳生成器；row 为行在本次写入该表的数据中的原始序号
+// 客户端时钟按调用顺序递增，调用方需按输入顺序调用（见 buildTableSegments）
+func newTSFiller(p TimestampPolicy, table string, clock *tsClock) tsFiller {
+	switch p {
+	case TimestampServerOffset:
+		return func(row int) (string, error) {
+			return fmt.Sprintf("NOW() + %da", row), nil
+		}
+	case TimestampClientClock:
+		return func(int) (string, error) {
+			return formatValue(clock.next(table, time.Now()))
+		}
+	case TimestampRequired:
+		return func(row int) (string, error) {
+			return "", fmt.Errorf("表 %s 第 %d 行缺少 ts", table, row)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 时间范围预聚合（TSMA）管理
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TSMAInfo 描述一个 TSMA（information_schema.ins_tsmas）
+type TSMAInfo struct {
+	Name      string
+	DB        string
+	Table     string // 源超级表或普通表
+	Interval  string // 如 5m
+	Functions string // 服务端记录的函数列表
+}
+
+// tsmaFunctions TSMA 支持的聚合函数；AVG 由服务端按 SUM/COUNT 计算
+var tsmaFunctions = map[string]bool{
+	"min": true, "max": true, "sum": true, "avg": true, "count": true,
+	"first": true, "last": true, "spread": true, "stddev": true, "hyperloglog": true,
+}
+
+var tsmaFuncPattern = regexp.MustCompile(`^\s*([A-Za-z_]+)\s*\(\s*([A-Za-z0-9_]+|\*)\s*\)\s*$`)
+
+// CreateTSMA 为超级表（或普通表）创建时间范围预聚合：
+// CREATE TSMA name ON stable FUNCTION(avg(current), max(voltage)) INTERVAL(5m)
+// functions 形如 "avg(current)"，函数须为 MIN、MAX、SUM、AVG、COUNT、FIRST、LAST、SPREAD、STDDEV、HYPERLOGLOG 之一，
+// 参数为列名（COUNT 可为 *）；interval 须为整分钟且不小于 1 分钟，上限以服务端为准
+// 创建后，窗口与之匹配的聚合查询（如 QueryAggregateAcrossStable）会由服务端自动使用 TSMA
+func (c *Client) CreateTSMA(name, stable string, functions []string, interval time.Duration) error {
+	sqlStr, err := buildCreateTSMASQL(name, stable, functions, interval)
+	if err != nil {
+		return err
+	}
+	_, err = c.DB.Exec(sqlStr)
+	return err
+}
+
+// buildCreateTSMASQL 生成 CREATE TSMA 语句
+func buildCreateTSMASQL(name, stable string, functions []string, interval time.Duration) (string, error) {
+	tsma, err := sanitizeIdent(name)
+	if err != nil {
+		return "", err
+	}
+	st, err := sanitizeIdent(stable)
+	if err != nil {
+		return "", err
+	}
+	if len(functions) == 0 {
+		return "", errors.New("functions 不能为空")
+	}
+	funcs := make([]string, 0, len(functions))
+	for _, f := range functions {
+		m := tsmaFuncPattern.FindStringSubmatch(f)
+		if m == nil {
+			return "", fmt.Errorf("非法的 TSMA 函数: %s", f)
+		}
+		fn := strings.ToLower(m[1])
+		if !tsmaFunctions[fn] {
+			return "", fmt.Errorf("TSMA 不支持函数 %s", m[1])
+		}
+		if m[2] == "*" && fn != "count" {
+			return "", fmt.Errorf("函数 %s 需指定列名", m[1])
+		}
+		funcs = append(funcs, fmt.Sprintf("%s(%s)", fn, m[2]))
+	}
+	iv, err := tsmaInterval(interval)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("CREATE TSMA %s ON %s FUNCTION(%s) INTERVAL(%s)", tsma, st, strings.Join(funcs, ", "), iv), nil
+}
+
+// tsmaInterval 将窗口长度格式化为 TSMA 的 INTERVAL 字面量（m/h/d）
+func tsmaInterval(d time.Duration) (string, error) {
+	if d < time.Minute || d%time.Minute != 0 {
+		return "", fmt.Errorf("TSMA 窗口须为整分钟且不小于 1 分钟: %s", d)
+	}
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour)), nil
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour), nil
+	}
+	return fmt.Sprintf("%dm", d/time.Minute), nil
+}
+
+// DropTSMA 删除 TSMA
+func (c *Client) DropTSMA(name string, ifExists bool) error {
+	tsma, err := sanitizeIdent(name)
+	if err != nil {
+		return err
+	}
+	sqlStr := "DROP TSMA "
+	if ifExists {
+		sqlStr += "IF EXISTS "
+	}
+	_, err = c.DB.Exec(sqlStr + tsma)
+	return err
+}
+
+// ListTSMAs 列出当前库中的 TSMA；stable 不为空时只列出该表上的 TSMA，可写作 db.stable 指定库
+func (c *Client) ListTSMAs(stable string) ([]TSMAInfo, error) {
+	q := "SELECT * FROM information_schema.ins_tsmas"
+	var conds []string
+	db := c.currentDB()
+	if stable != "" {
+		d, st, err := c.splitDBTable(stable)
+		if err != nil {
+			return nil, err
+		}
+		db = d
+		conds = append(conds, fmt.Sprintf("table_name = '%s'", st))
+	}
+	if db != "" {
+		conds = append(conds, fmt.Sprintf("db_name = '%s'", db))
+	}
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := c.queryMaps(q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TSMAInfo, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, TSMAInfo{
+			Name:      rowString(r, "tsma_name"),
+			DB:        rowString(r, "db_name"),
+			Table:     rowString(r, "table_name"),
+			Interval:  rowString(r, "interval"),
+			Functions: rowString(r, "func_list"),
+		})
+	}
+	return out, nil
+}
+
+// CreateTSMAMsg 创建 TSMA 并返回提示
+func (c *Client) CreateTSMAMsg(name, stable string, functions []string, interval time.Duration) (string, error) {
+	if err := c.CreateTSMA(name, stable, functions, interval); err != nil {
+		return "", fmt.Errorf("CreateTSMA %s failed: %w", name, err)
+	}
+	return fmt.Sprintf("TSMA 已创建: %s", name), nil
+}
+
+// DropTSMAMsg 删除 TSMA 并返回提示
+func (c *Client) DropTSMAMsg(name string) (string, error) {
+	if err := c.DropTSMA(name, true); err != nil {
+		return "", fmt.Errorf("DropTSMA %s failed: %w", name, err)
+	}
+	return fmt.Sprintf("TSMA 已删除: %s", name), nil
+}
+
+// ListTSMAsMsg 列出 TSMA 并返回提示
+func (c *Client) ListTSMAsMsg(stable string) ([]TSMAInfo, string, error) {
+	tsmas, err := c.ListTSMAs(stable)
+	if err != nil {
+		return nil, "", fmt.Errorf("ListTSMAs failed: %w", err)
+	}
+	return tsmas, fmt.Sprintf("共 %d 个 TSMA", len(tsmas)), nil
+}
@@ -12,15 +12,58 @@ package tdorm
 
 import (
 	"fmt"
-	"time"
+	"strings"
 )
 
 // ColumnDef 定义字段
 // Type 示例："INT", "FLOAT", "NCHAR(255)", "BINARY(64)" 等
 // 注意：TDengine 会强制存在 ts TIMESTAMP 字段，ORM 会自动添加
+// PrimaryKey 标记复合主键列（TDengine 3.3+）：紧随 ts 之后，(ts, 主键列) 共同确定一行，
+// 最多一个，类型须为 INT、BIGINT（可 UNSIGNED）或 VARCHAR/BINARY
+// Encode/Compress/Level 为列级压缩选项（TDengine 3.3+），为空时使用服务端默认值；名为 ts 的定义可设置时间戳列的选项
 type ColumnDef struct {
-	Name string
-	Type string
+	Name       string
+	Type       string
+	PrimaryKey bool
+	Encode     string // 一级编码：simple8b、delta-i、delta-d、bit-packing、disabled
+	Compress   string // 二级压缩：lz4、zlib、zstd、tsz、xz、disabled
+	Level      string // 压缩级别：high、medium、low
+}
+
+var (
+	columnEncodes  = []string{"simple8b", "delta-i", "delta-d", "bit-packing", "disabled"}
+	columnCompress = []string{"lz4", "zlib", "zstd", "tsz", "xz", "disabled"}
+	columnLevels   = []string{"high", "medium", "low"}
+)
+
+// columnOptions 生成列级压缩选项子句，如 " ENCODE 'delta-d' COMPRESS 'zstd' LEVEL 'high'"
+func columnOptions(col ColumnDef) (string, error) {
+	var sb strings.Builder
+	for _, opt := range []struct {
+		key, val string
+		allowed  []string
+	}{
+		{"ENCODE", col.Encode, columnEncodes},
+		{"COMPRESS", col.Compress, columnCompress},
+		{"LEVEL", col.Level, columnLevels},
+	} {
+		if opt.val == "" {
+			continue
+		}
+		v := strings.ToLower(strings.TrimSpace(opt.val))
+		ok := false
+		for _, a := range opt.allowed {
+			if v == a {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return "", fmt.Errorf("列 %s 的 %s 不支持: %s", col.Name, opt.key, opt.val)
+		}
+		fmt.Fprintf(&sb, " %s '%s'", opt.key, v)
+	}
+	return sb.String(), nil
 }
 
 // sanitizeIdent 检查并约束标识符，只允许字母、数字、下划线
@@ -36,36 +79,16 @@ func sanitizeIdent(id string) (string, error) {
 	return id, nil
 }
 
-// formatValue 将 Go 值格式化为 TDengine SQL 值
-func formatValue(v interface{}) (string, error) {
+// asString 将驱动返回的值统一转换为字符串
+func asString(v interface{}) string {
 	switch val := v.(type) {
 	case nil:
-		return "NULL", nil
+		return ""
 	case string:
-		// 转义单引号
-		esc := ""
-		for _, ch := range val {
-			if ch == '\'' {
-				esc += "''"
-			} else {
-				esc += string(ch)
-			}
-		}
-		return fmt.Sprintf("'%s'", esc), nil
-	case time.Time:
-		return fmt.Sprintf("'%s'", val.Format("2006-01-02 15:04:05.000")), nil
-	case int, int8, int16, int32, int64:
-		return fmt.Sprintf("%d", val), nil
-	case uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("%d", val), nil
-	case float32, float64:
-		return fmt.Sprintf("%v", val), nil
-	case bool:
-		if val {
-			return "1", nil
-		}
-		return "0", nil
+		return val
+	case []byte:
+		return string(val)
 	default:
-		return "", fmt.Errorf("不支持的值类型: %T", v)
+		return fmt.Sprintf("%v", val)
 	}
 }
@@ -0,0 +1,359 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 按时间戳更新（TDengine 3 通过相同主键重写实现部分列更新）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Upsert 按时间戳更新或插入一行：以相同 ts 重新写入 set 中的列，未出现的列保持原值
+// ts 可为 time.Time（毫秒精度）或按库精度的整数时间戳（微秒、纳秒库请使用整数）
+// 复合主键表以 (ts, 主键列) 确定一行，set 中须包含主键列的值
+func (c *Client) Upsert(table string, ts interface{}, set map[string]interface{}) error {
+	if ts == nil {
+		return errors.New("ts 不能为空")
+	}
+	if len(set) == 0 {
+		return errors.New("set 不能为空")
+	}
+	row := make(map[string]interface{}, len(set)+1)
+	for k, v := range set {
+		if strings.EqualFold(k, "ts") {
+			return errors.New("set 中不能包含 ts")
+		}
+		row[k] = v
+	}
+	tbl, err := sanitizeIdent(table)
+	if err != nil {
+		return err
+	}
+	pk, err := c.primaryKeyOf(tbl)
+	if err != nil {
+		return err
+	}
+	if pk != "" && !hasValue(set, pk) {
+		return fmt.Errorf("复合主键表 %s 需在 set 中提供主键列 %s", tbl, pk)
+	}
+	row["ts"] = ts
+	return c.Insert(tbl, row)
+}
+
+// UpdateOptions UpdateWhere 的配置
+type UpdateOptions struct {
+	DryRun    bool // 只统计将被修改的行，不写入
+	BatchSize int  // 每页读取并重写的行数，默认 1000
+}
+
+// UpdateTarget 被修改的一行：所在子表、按库精度的整数时间戳与复合主键值（没有时为 nil）
+type UpdateTarget struct {
+	Table string
+	TS    int64
+	Key   interface{}
+}
+
+// UpdateReport UpdateWhere 的结果
+type UpdateReport struct {
+	Matched int            // 匹配的行数
+	Written int            // 重写成功的行数（DryRun 时为 0）
+	Rows    []UpdateTarget // 匹配的行，仅 DryRun 时填充
+}
+
+// UpdateWhere 模拟 UPDATE ... SET ... WHERE：按 ts 升序分页查询匹配行的子表名与时间戳，
+// 每页以相同时间戳写入 set 中的列（其他列保持原值）。table 可为子表、普通表或超级表
+// 与 Delete 一样要求筛选条件非空；set 的列须为表中已有的普通列，此路径不会触发模式演进；
+// Filter.OrderBy 被忽略，Filter.Limit 限制处理的总行数；DryRun 时只返回匹配的行
+// 注意：读取与重写不是原子操作，在两者之间被删除的行会以只有 ts 与 set 列的部分行重新出现，
+// 其余列为 NULL；并发删除的场景请勿使用
+func (c *Client) UpdateWhere(table string, set map[string]interface{}, f Filter, opts UpdateOptions) (UpdateReport, error) {
+	var report UpdateReport
+	tbl, err := sanitizeIdent(table)
+	if err != nil {
+		return report, err
+	}
+	if len(set) == 0 {
+		return report, errors.New("set 不能为空")
+	}
+	for k := range set {
+		if strings.EqualFold(k, "ts") {
+			return report, errors.New("set 中不能包含 ts")
+		}
+		if _, err := sanitizeIdent(k); err != nil {
+			return report, err
+		}
+	}
+	where, err := f.buildWhere(c.codec())
+	if err != nil {
+		return report, err
+	}
+	if strings.TrimSpace(where) == "" {
+		return report, errors.New("危险操作：不允许无 WHERE 的更新")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	infos, err := c.DescribeStable(tbl)
+	if err != nil {
+		return report, err
+	}
+	pk, err := checkUpdateColumns(infos, set)
+	if err != nil {
+		return report, err
+	}
+	// 以整数读取时间戳，避免按毫秒格式化时丢失微秒、纳秒精度；复合主键列一并读出并原样写回
+	exprs := "tbname, CAST(ts AS BIGINT)"
+	if pk != "" {
+		exprs += ", " + pk
+	}
+	base := fmt.Sprintf("SELECT %s FROM %s WHERE (%s)", exprs, tbl, strings.TrimPrefix(where, "WHERE "))
+	batchOpts := c.batchOptions()
+	batchOpts.noEvolve = true
+	var last int64
+	for first := true; ; first = false {
+		limit := opts.BatchSize
+		if f.Limit > 0 && f.Limit-report.Matched < limit {
+			limit = f.Limit - report.Matched
+		}
+		if limit <= 0 {
+			break
+		}
+		page := base
+		if !first {
+			page += fmt.Sprintf(" AND ts > %d", last)
+		}
+		targets, err := c.queryUpdateTargets(fmt.Sprintf("%s ORDER BY ts LIMIT %d", page, limit), pk != "")
+		if err != nil {
+			return report, err
+		}
+		full := len(targets) == limit
+		if full {
+			// 与本页最后一行时间戳相同的行可能跨页，按时间戳补齐后下一页从更大的时间戳开始
+			last = targets[len(targets)-1].TS
+			for len(targets) > 0 && targets[len(targets)-1].TS == last {
+				targets = targets[:len(targets)-1]
+			}
+			ties, err := c.queryUpdateTargets(fmt.Sprintf("%s AND ts = %d", base, last), pk != "")
+			if err != nil {
+				return report, err
+			}
+			targets = append(targets, ties...)
+		}
+		if len(targets) > 0 {
+			report.Matched += len(targets)
+			if opts.DryRun {
+				report.Rows = append(report.Rows, targets...)
+			} else {
+				res, err := c.batchInsertTables(updateBatch(targets, pk, set), batchOpts)
+				report.Written += res.Written
+				if err != nil {
+					return report, err
+				}
+			}
+		}
+		if !full {
+			break
+		}
+	}
+	return report, nil
+}
+
+// checkUpdateColumns 检查 set 的列均为表中已有的普通列且不是复合主键列，返回复合主键列名
+func checkUpdateColumns(infos []ColumnInfo, set map[string]interface{}) (string, error) {
+	pk := ""
+	for _, info := range infos {
+		if info.PrimaryKey {
+			pk = info.Name
+		}
+	}
+	for k := range set {
+		var col *ColumnInfo
+		for i := range infos {
+			if strings.EqualFold(infos[i].Name, k) {
+				col = &infos[i]
+				break
+			}
+		}
+		switch {
+		case col == nil:
+			return "", fmt.Errorf("列 %s 不存在", k)
+		case col.IsTag:
+			return "", fmt.Errorf("%s 为 TAG 列，请使用 SetSubTableTag 修改", k)
+		case col.PrimaryKey:
+			return "", fmt.Errorf("set 中不能包含复合主键列 %s", k)
+		}
+	}
+	return pk, nil
+}
+
+// queryUpdateTargets 执行查询并读取 (tbname, ts[, 主键列])
+func (c *Client) queryUpdateTargets(sqlStr string, withKey bool) ([]UpdateTarget, error) {
+	rows, err := c.DB.Query(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []UpdateTarget
+	for rows.Next() {
+		var name, key interface{}
+		var ts int64
+		dest := []interface{}{&name, &ts}
+		if withKey {
+			dest = append(dest, &key)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		out = append(out, UpdateTarget{Table: asString(name), TS: ts, Key: key})
+	}
+	return out, rows.Err()
+}
+
+// updateBatch 将待修改的行按子表合并为写入数据，每行只包含 ts、复合主键列（pk 非空时）与 set 中的列
+func updateBatch(targets []UpdateTarget, pk string, set map[string]interface{}) []TableRows {
+	idx := map[string]int{}
+	var tables []TableRows
+	for _, t := range targets {
+		i, ok := idx[t.Table]
+		if !ok {
+			i = len(tables)
+			idx[t.Table] = i
+			tables = append(tables, TableRows{Table: t.Table})
+		}
+		row := make(map[string]interface{}, len(set)+1)
+		for k, v := range set {
+			row[k] = v
+		}
+		row["ts"] = t.TS
+		if pk != "" {
+			row[pk] = t.Key
+		}
+		tables[i].Rows = append(tables[i].Rows, row)
+	}
+	return tables
+}
+
+// primaryKeyOf 返回表的复合主键列名，没有时返回空串
+// 子表按所属超级表解析（优先使用子表缓存），结果按超级表或普通表缓存，
+// 由 InvalidateSchema 清除（DropTable、DropStable、AddColumnToStable 会调用）
+func (c *Client) primaryKeyOf(table string) (string, error) {
+	owner, err := c.keyOwner(table)
+	if err != nil {
+		return "", err
+	}
+	key := strings.ToLower(owner)
+	c.mu.RLock()
+	pk, ok := c.primaryKeys[key]
+	c.mu.RUnlock()
+	if ok {
+		return pk, nil
+	}
+	infos, err := c.DescribeStable(owner)
+	if err != nil {
+		return "", err
+	}
+	for _, info := range infos {
+		if info.PrimaryKey {
+			pk = info.Name
+			break
+		}
+	}
+	c.mu.Lock()
+	if c.primaryKeys == nil {
+		c.primaryKeys = make(map[string]string)
+	}
+	c.primaryKeys[key] = pk
+	c.mu.Unlock()
+	return pk, nil
+}
+
+// keyOwner 返回决定表结构的表：子表为所属超级表，超级表与普通表为其自身
+// 查询结果缓存在 keyOwners 中，由 InvalidateSchema 清除
+func (c *Client) keyOwner(table string) (string, error) {
+	key := strings.ToLower(table)
+	c.mu.RLock()
+	_, known := c.primaryKeys[key]
+	owner, cached := c.keyOwners[key]
+	c.mu.RUnlock()
+	if known {
+		return table, nil
+	}
+	if cached {
+		return owner, nil
+	}
+	if sc := c.subTableCache(); sc != nil {
+		if st, ok := sc.stableFor(table); ok {
+			return st, nil
+		}
+	}
+	st, err := c.stableOf(table)
+	if err != nil {
+		return "", err
+	}
+	if st == "" {
+		st = table
+	}
+	c.mu.Lock()
+	if c.keyOwners == nil {
+		c.keyOwners = make(map[string]string)
+	}
+	c.keyOwners[key] = st
+	c.mu.Unlock()
+	return st, nil
+}
+
+// checkDeleteKey TDengine 的 DELETE 只接受 ts（及超级表 TAG）上的条件，
+// 按复合主键列筛选时提前返回明确的错误；只有存在非 ts 条件时才查询表结构
+func (c *Client) checkDeleteKey(table string, f Filter) error {
+	pk, loaded := "", false
+	for _, cond := range f.Conditions {
+		if strings.EqualFold(cond.Column, "ts") {
+			continue
+		}
+		if !loaded {
+			var err error
+			if pk, err = c.primaryKeyOf(table); err != nil {
+				return err
+			}
+			loaded = true
+		}
+		if pk != "" && strings.EqualFold(cond.Column, pk) {
+			return fmt.Errorf("DELETE 只支持按 ts 与 TAG 筛选，不能按复合主键列 %s 删除单行", pk)
+		}
+	}
+	return nil
+}
+
+func hasValue(m map[string]interface{}, name string) bool {
+	for k, v := range m {
+		if strings.EqualFold(k, name) && v != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// UpsertMsg 按时间戳更新并返回提示
+func (c *Client) UpsertMsg(table string, ts interface{}, set map[string]interface{}) (string, error) {
+	if err := c.Upsert(table, ts, set); err != nil {
+		return "", fmt.Errorf("Upsert %s failed: %w", table, err)
+	}
+	return "更新成功", nil
+}
+
+// UpdateWhereMsg 按条件更新返回结果与提示
+func (c *Client) UpdateWhereMsg(table string, set map[string]interface{}, f Filter, opts UpdateOptions) (UpdateReport, string, error) {
+	report, err := c.UpdateWhere(table, set, f, opts)
+	if err != nil {
+		return report, "", fmt.Errorf("UpdateWhere %s failed: %w", table, err)
+	}
+	if opts.DryRun {
+		return report, fmt.Sprintf("预演：将更新 %d 行", report.Matched), nil
+	}
+	return report, fmt.Sprintf("更新成功，影响行数: %d", report.Written), nil
+}
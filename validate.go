@@ -0,0 +1,379 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 写入前按表结构校验并转换值
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errUnknownColumn 写入的列在表结构中不存在
+var errUnknownColumn = errors.New("列不存在")
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Table  string
+	Row    int // 在本次写入中的行序号（按表顺序展开）
+	Column string
+	Value  interface{}
+	Err    error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s 第 %d 行列 %s: %v", e.Table, e.Row, e.Column, e.Err)
+}
+
+// ValidationError 写入前校验失败，此时没有任何数据被发送
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 1 {
+		return "校验失败: " + e.Fields[0].Error()
+	}
+	return fmt.Sprintf("校验失败（%d 处）: %s 等", len(e.Fields), e.Fields[0].Error())
+}
+
+// EnableValidation 开启写入前校验：按 DESCRIBE 得到的表结构检查列是否存在，
+// 转换兼容的值（如整数写入 FLOAT、数字字符串写入 INT），拒绝溢出与超长字符串。
+// 作用于 Insert、BatchInsert、InsertAuto 及多表写入；表结构会被缓存，表结构变化后可调用 InvalidateSchema
+func (c *Client) EnableValidation() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validate = true
+	if c.schemas == nil {
+		c.schemas = make(map[string][]ColumnInfo)
+	}
+}
+
+// DisableValidation 关闭写入前校验并清空表结构缓存
+func (c *Client) DisableValidation() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validate = false
+	c.schemas = nil
+}
+
+// InvalidateSchema 使某个表（或超级表）的结构缓存失效；table 为空时清空全部
+func (c *Client) InvalidateSchema(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if table == "" {
+		c.primaryKeys = nil
+		c.keyOwners = nil
+		if c.schemas != nil {
+			c.schemas = make(map[string][]ColumnInfo)
+		}
+		return
+	}
+	key := strings.ToLower(table)
+	delete(c.primaryKeys, key)
+	delete(c.schemas, key)
+	delete(c.keyOwners, key)
+	// 删除或修改超级表时，其子表的映射一并失效
+	for sub, owner := range c.keyOwners {
+		if strings.EqualFold(owner, table) {
+			delete(c.keyOwners, sub)
+		}
+	}
+}
+
+// tableSchema 返回表结构，优先使用缓存
+func (c *Client) tableSchema(table string) ([]ColumnInfo, error) {
+	key := strings.ToLower(table)
+	c.mu.RLock()
+	infos, ok := c.schemas[key]
+	c.mu.RUnlock()
+	if ok {
+		return infos, nil
+	}
+	infos, err := c.DescribeStable(table)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.schemas != nil {
+		c.schemas[key] = infos
+	}
+	c.mu.Unlock()
+	return infos, nil
+}
+
+// validateTables 开启校验时按表结构检查并转换各行，返回转换后的数据；未开启时原样返回
+// 指定了 Stable 的表按超级表结构校验（子表可能尚未创建）
+func (c *Client) validateTables(tables []TableRows) ([]TableRows, error) {
+	c.mu.RLock()
+	enabled := c.validate
+	c.mu.RUnlock()
+	if !enabled {
+		return tables, nil
+	}
+	out := make([]TableRows, len(tables))
+	var errs []FieldError
+	base := 0
+	for i, t := range tables {
+		out[i] = t
+		if len(t.Rows) == 0 {
+			continue
+		}
+		name := t.Table
+		if t.Stable != "" {
+			name = t.Stable
+		}
+		if _, err := sanitizeIdent(name); err != nil {
+			return nil, err
+		}
+		infos, err := c.tableSchema(name)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Rows = make([]map[string]interface{}, len(t.Rows))
+		var se *SchemaEvolution
+		seLoaded := false
+		for j, row := range t.Rows {
+			coerced, fes := coerceRow(infos, row)
+			for _, fe := range fes {
+				if fe.Err == errUnknownColumn {
+					// 开启模式演进的超级表：未知列原样保留，写入失败后自动加列
+					if !seLoaded {
+						se, seLoaded = c.evolutionFor(t.Table, t.Stable), true
+					}
+					if se != nil && se.allows(fe.Column) {
+						coerced[fe.Column] = fe.Value
+						continue
+					}
+				}
+				fe.Table = t.Table
+				fe.Row = base + j
+				errs = append(errs, fe)
+			}
+			out[i].Rows[j] = coerced
+		}
+		base += len(t.Rows)
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+	return out, nil
+}
+
+// coerceRow 按表结构检查一行并转换值，返回新的 map（不修改原行）
+func coerceRow(infos []ColumnInfo, row map[string]interface{}) (map[string]interface{}, []FieldError) {
+	out := make(map[string]interface{}, len(row))
+	var errs []FieldError
+	for k, v := range row {
+		var col *ColumnInfo
+		if strings.EqualFold(k, "ts") && len(infos) > 0 {
+			col = &infos[0] // 首列为时间戳主键
+		} else {
+			for i := range infos {
+				if strings.EqualFold(infos[i].Name, k) {
+					col = &infos[i]
+					break
+				}
+			}
+		}
+		switch {
+		case col == nil:
+			errs = append(errs, FieldError{Column: k, Value: v, Err: errUnknownColumn})
+			continue
+		case col.IsTag:
+			errs = append(errs, FieldError{Column: k, Value: v, Err: fmt.Errorf("为 TAG 列，不能作为数据写入")})
+			continue
+		}
+		cv, err := coerceValue(v, *col)
+		if err != nil {
+			errs = append(errs, FieldError{Column: k, Value: v, Err: err})
+			continue
+		}
+		out[k] = cv
+	}
+	// 复合主键表的每一行都必须提供主键列
+	for _, info := range infos {
+		if !info.PrimaryKey {
+			continue
+		}
+		if !hasValue(row, info.Name) {
+			errs = append(errs, FieldError{Column: info.Name, Err: errors.New("复合主键列不能为空")})
+		}
+	}
+	return out, errs
+}
+
+// coerceValue 将 Go 值转换为与列类型兼容的值；nil 表示 NULL
+func coerceValue(v interface{}, col ColumnInfo) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	typ := strings.ToUpper(col.Type)
+	rv := reflect.ValueOf(v)
+	switch {
+	case typ == "TIMESTAMP":
+		switch v.(type) {
+		case time.Time, string:
+			return v, nil
+		}
+		if isIntKind(rv.Kind()) {
+			return v, nil
+		}
+	case typ == "BOOL":
+		switch val := v.(type) {
+		case bool:
+			return val, nil
+		case string:
+			if b, err := strconv.ParseBool(val); err == nil {
+				return b, nil
+			}
+		}
+	case strings.HasSuffix(typ, "INT UNSIGNED"):
+		return coerceUint(v, rv, typ)
+	case strings.HasSuffix(typ, "INT"):
+		return coerceInt(v, rv, typ)
+	case typ == "FLOAT" || typ == "DOUBLE":
+		return coerceFloat(v, rv, typ)
+	case typ == "JSON":
+		return v, nil
+	case isStringType(typ):
+		switch val := v.(type) {
+		case string:
+			if err := checkStringLength(val, typ, col.Length); err != nil {
+				return nil, err
+			}
+			return val, nil
+		case VarBinary:
+			if err := checkStringLength(string(val), "VARBINARY", col.Length); err != nil {
+				return nil, err
+			}
+			return val, nil
+		case []byte:
+			if err := checkStringLength(string(val), "VARBINARY", col.Length); err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(typ, "VARBINARY") || strings.HasPrefix(typ, "GEOMETRY") {
+				return VarBinary(val), nil
+			}
+			return string(val), nil
+		}
+	default:
+		// DECIMAL 等未识别的类型原样交给服务端处理
+		return v, nil
+	}
+	return nil, fmt.Errorf("%T 类型的值不能写入 %s 列", v, typ)
+}
+
+// isStringType 判断是否为字符串或字节串类型
+func isStringType(typ string) bool {
+	for _, p := range []string{"BINARY", "VARCHAR", "NCHAR", "VARBINARY", "GEOMETRY"} {
+		if strings.HasPrefix(typ, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNumeric 将字符串解析为数值列的值；空串不会被当作 NULL，NULL 请传 nil
+func parseNumeric(s, typ string) (interface{}, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("空字符串不能写入 %s 列", typ)
+	}
+	return parseColumnValue(s, ColumnInfo{Type: typ})
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// coerceInt 转换为有符号整数并检查范围
+func coerceInt(v interface{}, rv reflect.Value, typ string) (interface{}, error) {
+	bits := intTypeBits(typ)
+	var n int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d 超出 %s 范围", rv.Uint(), typ)
+		}
+		n = int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf("%v 不是 %s 范围内的整数", f, typ)
+		}
+		n = int64(f)
+	case reflect.String:
+		return parseNumeric(rv.String(), typ)
+	default:
+		return nil, fmt.Errorf("%T 类型的值不能写入 %s 列", v, typ)
+	}
+	if bits < 64 && (n < -(1<<(bits-1)) || n > 1<<(bits-1)-1) {
+		return nil, fmt.Errorf("%d 超出 %s 范围", n, typ)
+	}
+	return n, nil
+}
+
+// coerceUint 转换为无符号整数并检查范围
+func coerceUint(v interface{}, rv reflect.Value, typ string) (interface{}, error) {
+	bits := intTypeBits(typ)
+	var n uint64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return nil, fmt.Errorf("%d 超出 %s 范围", rv.Int(), typ)
+		}
+		n = uint64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return nil, fmt.Errorf("%v 不是 %s 范围内的整数", f, typ)
+		}
+		n = uint64(f)
+	case reflect.String:
+		return parseNumeric(rv.String(), typ)
+	default:
+		return nil, fmt.Errorf("%T 类型的值不能写入 %s 列", v, typ)
+	}
+	if bits < 64 && n > 1<<bits-1 {
+		return nil, fmt.Errorf("%d 超出 %s 范围", n, typ)
+	}
+	return n, nil
+}
+
+// coerceFloat 转换为浮点数，FLOAT 检查单精度范围
+func coerceFloat(v interface{}, rv reflect.Value, typ string) (interface{}, error) {
+	var f float64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	case reflect.String:
+		return parseNumeric(rv.String(), typ)
+	default:
+		return nil, fmt.Errorf("%T 类型的值不能写入 %s 列", v, typ)
+	}
+	if typ == "FLOAT" && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+		return nil, fmt.Errorf("%v 超出 FLOAT 范围", f)
+	}
+	return f, nil
+}
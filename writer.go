@@ -0,0 +1,248 @@
+/*
+ * @Author: GlennLiu <glennliu0607@gmail.com>
+ * @Date: 2026-10-18
+ * @Description: 后台缓冲写入器（按行数、字节数、延迟刷新）
+ *
+ * Copyright (c) 2026 by 天津晟源士兴科技有限公司, All Rights Reserved.
+ */
+package tdorm
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWriterFull 缓冲已满且配置为丢弃策略
+	ErrWriterFull = errors.New("writer 缓冲已满")
+	// ErrWriterClosed 写入器已关闭
+	ErrWriterClosed = errors.New("writer 已关闭")
+)
+
+// WriteRow 写入器接收的一行数据
+// Stable 非空时以 USING stable TAGS (...) 写入，子表不存在会自动创建
+type WriteRow struct {
+	Table  string
+	Stable string
+	Tags   []interface{}
+	Row    map[string]interface{}
+}
+
+// WriterOptions 缓冲写入器配置
+type WriterOptions struct {
+	MaxRows      int                                // 缓冲行数达到即刷新，默认 1000
+	MaxBytes     int                                // 缓冲估算字节数达到即刷新，默认 1MB
+	MaxLatency   time.Duration                      // 行在缓冲中的最长停留时间，默认 1s
+	MaxPending   int                                // 缓冲与刷新中的行数上限，默认 MaxRows*10
+	DropWhenFull bool                               // 达到上限时丢弃新行并返回 ErrWriterFull；否则 Write 阻塞等待（背压）
+	OnError      func(err error, failed []WriteRow) // 刷新失败回调，附带失败的行
+}
+
+// Writer 后台缓冲写入器，可被多个 goroutine 并发使用
+// 通过多表 INSERT 批量刷新；Write 之后调用方不应再修改 Row
+type Writer struct {
+	c    *Client
+	opts WriterOptions
+
+	mu       sync.Mutex
+	space    *sync.Cond // 缓冲有空位时通知
+	buf      []WriteRow
+	bytes    int
+	first    time.Time // 缓冲中最早一行的写入时间
+	inflight int       // 正在刷新的行数
+	closed   bool
+
+	flushMu sync.Mutex // 保证批次按顺序写入
+	kick    chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWriter 创建并启动缓冲写入器，使用完毕需调用 Close
+func (c *Client) NewWriter(opts WriterOptions) *Writer {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = 1000
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 20
+	}
+	if opts.MaxLatency <= 0 {
+		opts.MaxLatency = time.Second
+	}
+	if opts.MaxPending < opts.MaxRows {
+		opts.MaxPending = opts.MaxRows * 10
+	}
+	w := &Writer{c: c, opts: opts, kick: make(chan struct{}, 1), done: make(chan struct{})}
+	w.space = sync.NewCond(&w.mu)
+	w.wg.Add(1)
+	go w.loop()
+	return w
+}
+
+// Write 将一行放入缓冲；达到阈值时由后台异步刷新
+// 缓冲已满时按配置阻塞等待或返回 ErrWriterFull
+func (w *Writer) Write(r WriteRow) error {
+	if _, err := sanitizeIdent(r.Table); err != nil {
+		return err
+	}
+	size := estimateRowSize(r)
+	w.mu.Lock()
+	for !w.closed && len(w.buf)+w.inflight >= w.opts.MaxPending {
+		if w.opts.DropWhenFull {
+			w.mu.Unlock()
+			return ErrWriterFull
+		}
+		w.space.Wait()
+	}
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	if len(w.buf) == 0 {
+		w.first = time.Now()
+	}
+	w.buf = append(w.buf, r)
+	w.bytes += size
+	full := len(w.buf) >= w.opts.MaxRows || w.bytes >= w.opts.MaxBytes
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush 立即同步刷新当前缓冲
+func (w *Writer) Flush() error {
+	return w.flush()
+}
+
+// Close 停止后台刷新并写出剩余数据；重复调用返回 ErrWriterClosed
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	w.closed = true
+	w.space.Broadcast()
+	w.mu.Unlock()
+	close(w.done)
+	w.wg.Wait()
+	return w.flush()
+}
+
+func (w *Writer) loop() {
+	defer w.wg.Done()
+	tick := w.opts.MaxLatency / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.kick:
+			w.flush()
+		case <-ticker.C:
+			w.mu.Lock()
+			due := len(w.buf) > 0 && time.Since(w.first) >= w.opts.MaxLatency
+			w.mu.Unlock()
+			if due {
+				w.flush()
+			}
+		}
+	}
+}
+
+// flush 取出当前缓冲并以多表 INSERT 写入，失败时回调 OnError
+func (w *Writer) flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	w.mu.Lock()
+	rows := w.buf
+	w.buf = nil
+	w.bytes = 0
+	w.first = time.Time{}
+	w.inflight += len(rows)
+	w.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+	tables, order := groupWriteRows(rows)
+	res, err := w.c.BatchInsertTablesWithResult(tables)
+	if err != nil && w.opts.OnError != nil {
+		failed := rows
+		if len(res.Chunks) > 0 {
+			// 仅回调所在语句失败的行
+			failed = nil
+			for _, ch := range res.Chunks {
+				if ch.Err != nil {
+					for _, i := range ch.Indexes {
+						failed = append(failed, rows[order[i]])
+					}
+				}
+			}
+		}
+		w.opts.OnError(err, failed)
+	}
+	w.mu.Lock()
+	w.inflight -= len(rows)
+	w.space.Broadcast()
+	w.mu.Unlock()
+	return err
+}
+
+// groupWriteRows 按表名合并行，保持各表首次出现的顺序
+// 同时返回合并后第 k 行在 rows 中的下标，与 BatchResult 中的行序号对应
+func groupWriteRows(rows []WriteRow) ([]TableRows, []int) {
+	idx := map[string]int{}
+	tables := []TableRows{}
+	members := [][]int{}
+	for n, r := range rows {
+		key := strings.ToLower(r.Table)
+		i, ok := idx[key]
+		if !ok {
+			i = len(tables)
+			idx[key] = i
+			tables = append(tables, TableRows{Table: r.Table})
+			members = append(members, nil)
+		}
+		if tables[i].Stable == "" && r.Stable != "" {
+			tables[i].Stable = r.Stable
+			tables[i].Tags = r.Tags
+		}
+		tables[i].Rows = append(tables[i].Rows, r.Row)
+		members[i] = append(members[i], n)
+	}
+	order := make([]int, 0, len(rows))
+	for _, m := range members {
+		order = append(order, m...)
+	}
+	return tables, order
+}
+
+// estimateRowSize 估算一行在 SQL 中占用的字节数
+func estimateRowSize(r WriteRow) int {
+	size := len(r.Table) + 4
+	for k, v := range r.Row {
+		size += len(k) + 2
+		switch val := v.(type) {
+		case string:
+			size += len(val) + 2
+		case []byte:
+			size += len(val)*2 + 3
+		case time.Time:
+			size += 25
+		default:
+			size += 20 // 数值与布尔的上限估计
+		}
+	}
+	return size
+}